@@ -1,6 +1,7 @@
 package config
 
 import (
+	"fmt"
 	"io/ioutil"
 	"log"
 	"strings"
@@ -15,9 +16,29 @@ type ValidatorConf struct {
 	Pattern   *string `yaml:"Pattern"`
 }
 
+// ScalarConf is a custom scalar of the validator.yaml file, documented in rest.yaml, eg.
+//
+//	Scalars:
+//	  - Name: UUID
+//	    Format: uuid
+//	    Pattern: "^[0-9a-f]{8}(-[0-9a-f]{4}){3}-[0-9a-f]{12}$"
+//
+// The type is "string" if not set, see scalar.Scalar
+type ScalarConf struct {
+	Name      string `yaml:"Name"`
+	Type      string `yaml:"Type"`
+	Format    string `yaml:"Format"`
+	Pattern   string `yaml:"Pattern"`
+	MinLength *int64 `yaml:"MinLength"`
+	MaxLength *int64 `yaml:"MaxLength"`
+}
+
 var validators []ValidatorConf
+var scalars []ScalarConf
 var yamlFilePath string
 var docTitle string
+var maxSelectionDepth int
+var problemDetails bool
 
 func SetDocTitle(t string) {
 	docTitle = t
@@ -27,6 +48,25 @@ func GetDocTitle() string {
 	return docTitle
 }
 
+// SetMaxSelectionDepth limits the nesting of the generated REST selections, 0 means unlimited
+func SetMaxSelectionDepth(depth int) {
+	maxSelectionDepth = depth
+}
+
+func GetMaxSelectionDepth() int {
+	return maxSelectionDepth
+}
+
+// SetProblemDetails writes and documents the REST errors as RFC 7807 problem documents,
+// the generated RegisterHandlers sets the error format of the mapping with it, see handlerx.ProblemErrorFormat
+func SetProblemDetails(enabled bool) {
+	problemDetails = enabled
+}
+
+func GetProblemDetails() bool {
+	return problemDetails
+}
+
 func SetYamlFilePath(p string) {
 	yamlFilePath = p
 }
@@ -35,29 +75,77 @@ func GetYamlFilePath() string {
 	return yamlFilePath
 }
 
+// InitValidatorConfig reads the validators and the scalars of the generator from a validator.yaml file,
+// they are reset if the file is not set or cannot be read
 func InitValidatorConfig(filename string) {
-	var res struct {
-		Validators []ValidatorConf `yaml:"Validators"`
-	}
+	validators = nil
+	scalars = nil
 
 	if filename == "" {
 		log.Println("WARNING: validator file not set")
 		return
 	}
 
-	file, err := ioutil.ReadFile(filename)
+	res, err := readValidatorFile(filename)
 	if err != nil {
-		log.Println("WARNING: read validator file error:", err.Error())
+		log.Println("WARNING:", err.Error())
 		return
 	}
 
+	validators = res.Validators
+	scalars = res.Scalars
+}
+
+// validatorFile is the content of a validator.yaml file
+type validatorFile struct {
+	Validators []ValidatorConf `yaml:"Validators"`
+	Scalars    []ScalarConf    `yaml:"Scalars"`
+}
+
+func readValidatorFile(filename string) (*validatorFile, error) {
+	var res validatorFile
+
+	file, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("read validator file error: %w", err)
+	}
+
 	err = yaml.Unmarshal(file, &res)
 	if err != nil {
-		log.Println("WARNING: unmarshal validator file error:", err.Error())
-		return
+		return nil, fmt.Errorf("unmarshal validator file error: %w", err)
 	}
 
-	validators = res.Validators
+	return &res, nil
+}
+
+// ReadValidatorConfig reads the validators of a validator.yaml file
+func ReadValidatorConfig(filename string) ([]ValidatorConf, error) {
+	res, err := readValidatorFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	return res.Validators, nil
+}
+
+// ReadScalarConfig reads the scalars of a validator.yaml file
+func ReadScalarConfig(filename string) ([]ScalarConf, error) {
+	res, err := readValidatorFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	return res.Scalars, nil
+}
+
+// GetValidators returns the validators read by InitValidatorConfig
+func GetValidators() []ValidatorConf {
+	return validators
+}
+
+// GetScalars returns the scalars read by InitValidatorConfig
+func GetScalars() []ScalarConf {
+	return scalars
 }
 
 func GetValidatorByFormat(format string) *ValidatorConf {
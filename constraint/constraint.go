@@ -0,0 +1,308 @@
+// Package constraint reads the @constraintNumber, @constraintString, @constraintSlice and
+// @constraintStringSlice directives of arguments and input fields. The same rules are written
+// into the OpenAPI documents by restgen and enforced at runtime by handlerx.ConstraintValidator,
+// with the same formats, see Formats.
+package constraint
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	validatorConfig "github.com/speedoops/go-gqlrest/config"
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+// Directives are the constraint directives, the first one found on a definition is used
+var Directives = []string{"constraintNumber", "constraintString", "constraintSlice", "constraintStringSlice"}
+
+// Format is a named string format of the directives, eg. `@constraintString(format: "hostname")`
+type Format struct {
+	Pattern string
+	// 0 if not limited
+	MinLength int64
+	MaxLength int64
+}
+
+// Formats are the formats by name, as defined in validator.yaml. The generated code embeds the
+// formats the generator was given, so that the server enforces the documented rules.
+type Formats map[string]Format
+
+// FormatsOf returns the formats of the validators of a validator.yaml file
+func FormatsOf(validators []validatorConfig.ValidatorConf) Formats {
+	formats := make(Formats, len(validators))
+	for _, va := range validators {
+		var format Format
+		if va.Pattern != nil {
+			format.Pattern = *va.Pattern
+		}
+		if va.MinLength != nil {
+			format.MinLength = *va.MinLength
+		}
+		if va.MaxLength != nil {
+			format.MaxLength = *va.MaxLength
+		}
+		formats[strings.ReplaceAll(va.Name, "\"", "")] = format
+	}
+	return formats
+}
+
+// LoadFormats reads the formats of a validator.yaml file
+func LoadFormats(filename string) (Formats, error) {
+	validators, err := validatorConfig.ReadValidatorConfig(filename)
+	if err != nil {
+		return nil, err
+	}
+	return FormatsOf(validators), nil
+}
+
+// Rule is the constraint of an argument or an input field
+type Rule struct {
+	Minimum   *float64 //Number取值限制
+	Maximum   *float64
+	OneOf     []float64 //数字枚举
+	MinLength *int64    //字符串长度限制
+	MaxLength *int64
+	Format    *string // validator.yaml 中的格式名
+	Pattern   *string
+	MinItems  *int64 //切片元素数量限制
+	MaxItems  *int64
+	// Items applies the string rules to the items of a list, for @constraintStringSlice
+	Items bool
+	// UnknownFormat is the format name of the directive if not in the formats, its rules are not checked
+	UnknownFormat string
+
+	pattern *regexp.Regexp
+}
+
+// Violation is a value breaking a rule
+type Violation struct {
+	// Constraint is the name of the broken rule, eg. "maxLength"
+	Constraint string
+	Message    string
+}
+
+// Has reports whether a definition has a constraint directive
+func Has(directives ast.DirectiveList) bool {
+	for _, directiveName := range Directives {
+		if directives.ForName(directiveName) != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// ForDirectives returns the rule of the constraint directive of a definition, or nil if it has none
+func ForDirectives(name string, directives ast.DirectiveList, formats Formats) *Rule {
+	for _, directiveName := range Directives {
+		if directive := directives.ForName(directiveName); directive != nil {
+			return Parse(name, directive, formats)
+		}
+	}
+	return nil
+}
+
+// Parse reads the rule of a constraint directive, the invalid arguments are logged and ignored
+func Parse(variableName string, directive *ast.Directive, formats Formats) *Rule {
+	obj := &Rule{Items: directive.Name == "constraintStringSlice"}
+	minimum := directive.Arguments.ForName("min")
+	if minimum == nil {
+		minimum = directive.Arguments.ForName("minimum")
+	}
+
+	if minimum != nil {
+		num, err := strconv.ParseFloat(minimum.Value.String(), 64)
+		if err != nil {
+			log.Printf("parse variable:%v minimum value:%v to float error:%v", variableName, minimum.Value.String(), err.Error())
+		} else {
+			obj.Minimum = &num
+		}
+	}
+
+	maximum := directive.Arguments.ForName("max")
+	if maximum == nil {
+		maximum = directive.Arguments.ForName("maximum")
+	}
+
+	if maximum != nil {
+		num, err := strconv.ParseFloat(maximum.Value.String(), 64)
+		if err != nil {
+			log.Printf("parse variable:%v maximum value:%v to float error:%v", variableName, maximum.Value.String(), err.Error())
+		} else {
+			obj.Maximum = &num
+		}
+	}
+
+	oneOf := directive.Arguments.ForName("oneOf")
+	if oneOf != nil {
+		v := oneOf.Value.String()
+		v = v[1 : len(v)-1]
+		values := strings.Split(v, ",")
+		array := make([]float64, 0, len(values))
+		for _, a := range values {
+			num, err := strconv.ParseFloat(strings.TrimSpace(a), 64)
+			if err != nil {
+				log.Printf("parse variable:%v oneOf value:%v to float error:%v", variableName, v, err.Error())
+			} else {
+				array = append(array, num)
+			}
+		}
+		if len(array) > 0 {
+			obj.OneOf = array
+		}
+	}
+
+	if format := directive.Arguments.ForName("format"); format != nil {
+		// 读取外部配置
+		formatValue := format.Value.String()
+		obj.Format = &formatValue
+		if va, ok := formats[strings.ReplaceAll(formatValue, "\"", "")]; ok {
+			if va.Pattern != "" {
+				obj.Pattern = &va.Pattern
+			}
+			if va.MinLength != 0 {
+				obj.MinLength = &va.MinLength
+			}
+			if va.MaxLength != 0 {
+				obj.MaxLength = &va.MaxLength
+			}
+		} else {
+			obj.UnknownFormat = strings.ReplaceAll(formatValue, "\"", "")
+			log.Printf("WARNING: format '%s' not found in validator.yaml.\n", formatValue)
+		}
+	}
+
+	obj.MinLength = parseInt(variableName, directive, "minLength", obj.MinLength)
+	obj.MaxLength = parseInt(variableName, directive, "maxLength", obj.MaxLength)
+	obj.MinItems = parseInt(variableName, directive, "minItems", obj.MinItems)
+	obj.MaxItems = parseInt(variableName, directive, "maxItems", obj.MaxItems)
+
+	if obj.Pattern != nil {
+		re, err := regexp.Compile(*obj.Pattern)
+		if err != nil {
+			log.Printf("WARNING: variable:%v pattern:%v is not a valid regexp:%v", variableName, *obj.Pattern, err.Error())
+		} else {
+			obj.pattern = re
+		}
+	}
+
+	return obj
+}
+
+// parseInt reads an integer argument of a directive, or returns the default value if not set or invalid
+func parseInt(variableName string, directive *ast.Directive, name string, defaultValue *int64) *int64 {
+	arg := directive.Arguments.ForName(name)
+	if arg == nil {
+		return defaultValue
+	}
+
+	num, err := strconv.ParseInt(arg.Value.String(), 10, 64)
+	if err != nil {
+		log.Printf("parse variable:%v %s value:%v to int error:%v", variableName, name, arg.Value.String(), err.Error())
+		return defaultValue
+	}
+	return &num
+}
+
+// Check returns the violations of the rule by a value, as decoded from JSON or from a GraphQL literal.
+// Null values are not checked, they are rejected by the non-null types.
+func (r *Rule) Check(value interface{}) []Violation {
+	violations := make([]Violation, 0)
+	switch v := value.(type) {
+	case nil:
+	case []interface{}:
+		if r.MinItems != nil && int64(len(v)) < *r.MinItems {
+			violations = append(violations, Violation{"minItems", fmt.Sprintf("must have at least %d items", *r.MinItems)})
+		}
+		if r.MaxItems != nil && int64(len(v)) > *r.MaxItems {
+			violations = append(violations, Violation{"maxItems", fmt.Sprintf("must have at most %d items", *r.MaxItems)})
+		}
+		if r.Items {
+			for i, item := range v {
+				if s, ok := item.(string); ok {
+					for _, violation := range r.checkString(s) {
+						violation.Message = fmt.Sprintf("item %d %s", i, violation.Message)
+						violations = append(violations, violation)
+					}
+				}
+			}
+		}
+	case string:
+		if !r.Items {
+			violations = append(violations, r.checkString(v)...)
+		}
+	default:
+		if num, ok := toFloat(v); ok {
+			violations = append(violations, r.checkNumber(num)...)
+		}
+	}
+	return violations
+}
+
+func (r *Rule) checkString(s string) []Violation {
+	violations := make([]Violation, 0)
+	length := int64(utf8.RuneCountInString(s))
+	if r.MinLength != nil && length < *r.MinLength {
+		violations = append(violations, Violation{"minLength", fmt.Sprintf("must be at least %d characters long", *r.MinLength)})
+	}
+	if r.MaxLength != nil && length > *r.MaxLength {
+		violations = append(violations, Violation{"maxLength", fmt.Sprintf("must be at most %d characters long", *r.MaxLength)})
+	}
+	if r.pattern != nil && !r.pattern.MatchString(s) {
+		message := "must match " + r.pattern.String()
+		if r.Format != nil {
+			message = "must be a valid " + strings.Trim(*r.Format, "\"")
+		}
+		violations = append(violations, Violation{"pattern", message})
+	}
+	return violations
+}
+
+func (r *Rule) checkNumber(num float64) []Violation {
+	violations := make([]Violation, 0)
+	if r.Minimum != nil && num < *r.Minimum {
+		violations = append(violations, Violation{"minimum", "must be at least " + formatFloat(*r.Minimum)})
+	}
+	if r.Maximum != nil && num > *r.Maximum {
+		violations = append(violations, Violation{"maximum", "must be at most " + formatFloat(*r.Maximum)})
+	}
+	if len(r.OneOf) > 0 {
+		found := false
+		values := make([]string, 0, len(r.OneOf))
+		for _, v := range r.OneOf {
+			found = found || v == num
+			values = append(values, formatFloat(v))
+		}
+		if !found {
+			violations = append(violations, Violation{"oneOf", "must be one of " + strings.Join(values, ", ")})
+		}
+	}
+	return violations
+}
+
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, true
+	case json.Number:
+		num, err := v.Float64()
+		return num, err == nil
+	}
+	return 0, false
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
@@ -0,0 +1,86 @@
+package constraint
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vektah/gqlparser/v2"
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+func TestRule(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "validator.yaml")
+	require.NoError(t, ioutil.WriteFile(file, []byte(`
+Validators:
+  - Name: "hostname"
+    MaxLength: 16
+    Pattern: "^[a-z][a-z0-9-]*$"
+`), 0o600))
+	formats, err := LoadFormats(file)
+	require.NoError(t, err)
+
+	schema := gqlparser.MustLoadSchema(&ast.Source{Input: `
+		directive @constraintNumber(min: Float, max: Float, oneOf: [Float!]) on ARGUMENT_DEFINITION
+		directive @constraintString(minLength: Int, maxLength: Int, format: String) on ARGUMENT_DEFINITION
+		directive @constraintSlice(minItems: Int, maxItems: Int) on ARGUMENT_DEFINITION
+		directive @constraintStringSlice(minItems: Int, maxItems: Int, maxLength: Int) on ARGUMENT_DEFINITION
+		type Query {
+			hosts(
+				limit: Int @constraintNumber(min: 1, max: 100)
+				level: Int @constraintNumber(oneOf: [1, 2, 3])
+				name: String @constraintString(minLength: 2, maxLength: 8)
+				hostname: String @constraintString(format: "hostname")
+				ids: [ID!] @constraintSlice(maxItems: 2)
+				tags: [String!] @constraintStringSlice(maxItems: 2, maxLength: 3)
+				free: String
+			): Int
+		}
+	`})
+	field := schema.Query.Fields.ForName("hosts")
+	rule := func(name string) *Rule {
+		arg := field.Arguments.ForName(name)
+		return ForDirectives(arg.Name, arg.Directives, formats)
+	}
+
+	tests := []struct {
+		Arg      string
+		Value    interface{}
+		Expected []Violation
+	}{
+		{"limit", 10, []Violation{}},
+		{"limit", int64(0), []Violation{{"minimum", "must be at least 1"}}},
+		{"limit", json.Number("101"), []Violation{{"maximum", "must be at most 100"}}},
+		{"level", 2.0, []Violation{}},
+		{"level", 4, []Violation{{"oneOf", "must be one of 1, 2, 3"}}},
+		{"name", "中文", []Violation{}},
+		{"name", "a", []Violation{{"minLength", "must be at least 2 characters long"}}},
+		{"name", "abcdefghi", []Violation{{"maxLength", "must be at most 8 characters long"}}},
+		{"hostname", "web-01", []Violation{}},
+		{"hostname", "Web_01", []Violation{{"pattern", "must be a valid hostname"}}},
+		{"hostname", "web-0123456789abcdef", []Violation{{"maxLength", "must be at most 16 characters long"}}},
+		{"ids", []interface{}{"1", "2", "3"}, []Violation{{"maxItems", "must have at most 2 items"}}},
+		{"tags", []interface{}{"a", "abcd"}, []Violation{{"maxLength", "item 1 must be at most 3 characters long"}}},
+		{"tags", "abcd", []Violation{}},
+		{"limit", nil, []Violation{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Arg, func(t *testing.T) {
+			r := rule(tt.Arg)
+			require.NotNil(t, r)
+			assert.Equal(t, tt.Expected, r.Check(tt.Value))
+		})
+	}
+
+	assert.Nil(t, rule("free"))
+	assert.False(t, Has(field.Arguments.ForName("free").Directives))
+
+	// without the formats of validator.yaml the format is reported as unknown
+	hostname := field.Arguments.ForName("hostname")
+	assert.Equal(t, "", rule("hostname").UnknownFormat)
+	assert.Equal(t, "hostname", ForDirectives(hostname.Name, hostname.Directives, nil).UnknownFormat)
+}
@@ -0,0 +1,382 @@
+package handlerx
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/99designs/gqlgen/graphql"
+)
+
+// BindBatch mounts the batch route of the mapping at pattern, eg. "/api/batch", served by srv
+// with the Batch transport. Its body is a list of REST calls to the routes of the mapping:
+//
+//	[
+//		{"method": "GET", "path": "/todos", "query": {"limit": 10}},
+//		{"method": "POST", "path": "/todos", "body": {"text": "buy milk"}}
+//	]
+//
+// and its response the list of their responses in the same order, see BatchResult.
+// The number of items and the size of the body are limited, see Batch.MaxItems and Batch.MaxBodySize.
+func (m *Mapping) BindBatch(binder RouteBinder, srv http.Handler, pattern string) {
+	m.mu.Lock()
+	m.batchPattern = pattern
+	m.mu.Unlock()
+
+	binder.Bind(http.MethodPost, pattern, withBoundRoute(pattern, binder, m.Handler(srv)))
+	binder.Bind(http.MethodOptions, pattern, withBoundRoute(pattern, binder, m.optionsHandler()))
+}
+
+// isBatchRoute reports whether a route pattern is the batch route of the mapping
+func (m *Mapping) isBatchRoute(pattern string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.batchPattern != "" && pattern == m.batchPattern
+}
+
+// matchRoute returns the REST route of a request path and its path parameters. Routes with
+// more literal segments win, eg. "/todos/events" over "/todos/{id}".
+func (m *Mapping) matchRoute(method string, path string) (string, []string, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	pattern, values, best := "", []string(nil), -1
+	for _, p := range m.routePatterns {
+		if p.method != method {
+			continue
+		}
+		if v, literals, ok := p.match(path); ok && literals > best {
+			pattern, values, best = p.pattern, v, literals
+		}
+	}
+	return pattern, values, best >= 0
+}
+
+// routePattern is a route pattern compiled once, when the routes of the mapping are set
+type routePattern struct {
+	// route is the key of the route, eg. "GET:/todos/{id}"
+	route    string
+	method   string
+	pattern  string
+	segments []string
+	// regexps of the path parameters with one, by segment, eg. "{id:[0-9]+}"
+	regexps map[int]*regexp.Regexp
+	// invalid is set if a regexp does not compile, the pattern matches no path
+	invalid bool
+}
+
+// compileRoutePattern compiles the pattern of a route key, in the forms accepted by patternParams
+func compileRoutePattern(route string) *routePattern {
+	kv := strings.SplitN(route, ":", 2)
+	if len(kv) < 2 {
+		kv = append(kv, "")
+	}
+	p := &routePattern{route: route, method: kv[0], pattern: kv[1], segments: strings.Split(kv[1], "/")}
+	for i, segment := range p.segments {
+		if !strings.HasPrefix(segment, "{") || !strings.HasSuffix(segment, "}") {
+			continue
+		}
+		if j := strings.Index(segment, ":"); j >= 0 {
+			re, err := regexp.Compile("^(?:" + segment[j+1:len(segment)-1] + ")$")
+			if err != nil {
+				p.invalid = true
+				continue
+			}
+			if p.regexps == nil {
+				p.regexps = make(map[int]*regexp.Regexp)
+			}
+			p.regexps[i] = re
+		}
+	}
+	return p
+}
+
+// match matches a path with the route pattern, and returns the values of its path parameters
+// and the number of its literal segments
+func (p *routePattern) match(path string) ([]string, int, bool) {
+	if p.invalid {
+		return nil, 0, false
+	}
+	patternSegments := p.segments
+	pathSegments := strings.Split(path, "/")
+
+	values, literals := make([]string, 0), 0
+	for i, segment := range patternSegments {
+		if segment == "*" || (strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "...}")) {
+			if i > len(pathSegments) {
+				return nil, 0, false
+			}
+			if segment != "*" {
+				values = append(values, strings.Join(pathSegments[i:], "/"))
+			}
+			return values, literals, true
+		}
+		if i >= len(pathSegments) {
+			return nil, 0, false
+		}
+		if segment == "{$}" {
+			if pathSegments[i] != "" || i != len(pathSegments)-1 {
+				return nil, 0, false
+			}
+			continue
+		}
+		if !strings.HasPrefix(segment, "{") || !strings.HasSuffix(segment, "}") {
+			if segment != pathSegments[i] {
+				return nil, 0, false
+			}
+			if segment != "" {
+				literals++
+			}
+			continue
+		}
+
+		value := pathSegments[i]
+		if value == "" {
+			return nil, 0, false
+		}
+		if re, ok := p.regexps[i]; ok && !re.MatchString(value) {
+			return nil, 0, false
+		}
+		values = append(values, value)
+	}
+
+	if len(patternSegments) != len(pathSegments) {
+		return nil, 0, false
+	}
+	return values, literals, true
+}
+
+const (
+	// DefaultBatchMaxItems is the number of items of a batch request accepted by default
+	DefaultBatchMaxItems = 100
+	// DefaultBatchMaxBodySize is the size of the body of a batch request accepted by default, 10 MiB
+	DefaultBatchMaxBodySize = 10 << 20
+)
+
+// Batch implements the batch route of REST mappings, see Mapping.BindBatch. Each item is converted
+// by the mapping, like the request of its route, and executed by the REST transport of its method.
+// It must be added to the server before POST, which would otherwise take the batch route.
+type Batch struct {
+	// Mapping converts REST requests, the one carried by the request context is used if nil
+	Mapping *Mapping
+	// Concurrency is the number of items executed at once, the items are executed one by one if not set
+	Concurrency int
+	// Tracer traces the items as requests of the trace of the batch request if set, see WithTracer
+	Tracer *Tracer
+	// MaxItems is the number of items of a batch request, DefaultBatchMaxItems if not set.
+	// Larger batches are rejected with 413.
+	MaxItems int
+	// MaxBodySize is the size in bytes of the body of a batch request, DefaultBatchMaxBodySize if not set.
+	// Larger bodies are rejected with 413.
+	MaxBodySize int64
+}
+
+var _ graphql.Transport = Batch{}
+
+// batchItem is one REST call of a batch request
+type batchItem struct {
+	Method string                 `json:"method"`
+	Path   string                 `json:"path"`
+	Query  map[string]interface{} `json:"query,omitempty"`
+	Body   json.RawMessage        `json:"body,omitempty"`
+}
+
+// BatchResult is the response to one REST call of a batch request
+type BatchResult struct {
+	// Status is the HTTP status of the call
+	Status int `json:"status"`
+	// Body is the REST response envelope of the call, empty for 204 No Content
+	Body json.RawMessage `json:"body,omitempty"`
+}
+
+func (h Batch) Supports(r *http.Request) bool {
+	if r.Header.Get("Upgrade") != "" || r.Method != http.MethodPost {
+		return false
+	}
+
+	mapping := requestMapping(h.Mapping, r)
+	if mapping == nil {
+		return false
+	}
+	pattern, _, _ := requestRoute(r)
+	return mapping.isBatchRoute(pattern)
+}
+
+func (h Batch) Do(w http.ResponseWriter, r *http.Request, exec graphql.GraphExecutor) {
+	w.Header().Set("Content-Type", "application/json")
+	mapping := requestMapping(h.Mapping, r)
+	ctx := createResponseContext(WithMapping(r.Context(), mapping))
+	// the calls of the batch share its request ID
+	setRequestID(ctx, w, r)
+
+	maxBodySize := h.MaxBodySize
+	if maxBodySize <= 0 {
+		maxBodySize = DefaultBatchMaxBodySize
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, maxBodySize+1))
+	if err != nil {
+		writeMappingError(ctx, w, true, "", &mappingError{code: http.StatusBadRequest, msg: "batch body could not be read: " + err.Error()})
+		return
+	}
+	if int64(len(body)) > maxBodySize {
+		writeMappingError(ctx, w, true, "", &mappingError{code: http.StatusRequestEntityTooLarge,
+			msg: fmt.Sprintf("batch body is larger than %d bytes", maxBodySize)})
+		return
+	}
+
+	var items []*batchItem
+	if err := jsonDecode(bytes.NewReader(body), &items); err != nil {
+		writeMappingError(ctx, w, true, "", &mappingError{code: http.StatusBadRequest, msg: "batch body could not be decoded: " + err.Error()})
+		return
+	}
+	maxItems := h.MaxItems
+	if maxItems <= 0 {
+		maxItems = DefaultBatchMaxItems
+	}
+	if len(items) > maxItems {
+		writeMappingError(ctx, w, true, "", &mappingError{code: http.StatusRequestEntityTooLarge,
+			msg: fmt.Sprintf("batch has %d items, at most %d are accepted", len(items), maxItems)})
+		return
+	}
+
+	concurrency := h.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	results := make([]*BatchResult, len(items))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, item := range items {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, item *batchItem) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			results[i] = h.execute(r, mapping, item, exec)
+		}(i, item)
+	}
+	wg.Wait()
+
+	b, err := json.Marshal(results)
+	if err != nil {
+		panic(err)
+	}
+	if _, err := w.Write(b); err != nil {
+		panic(err)
+	}
+}
+
+// execute runs one REST call of a batch request with the transport of its method
+func (h Batch) execute(r *http.Request, mapping *Mapping, item *batchItem, exec graphql.GraphExecutor) *BatchResult {
+	w := &batchResponseWriter{header: make(http.Header)}
+	fail := func(code int, msg string) *BatchResult {
+		ctx := createResponseContext(WithMapping(r.Context(), mapping))
+		setRequestID(ctx, w, r)
+		writeMappingError(ctx, w, true, "", &mappingError{code: code, msg: msg})
+		return w.result()
+	}
+
+	if item == nil {
+		return fail(http.StatusBadRequest, "invalid batch item: null")
+	}
+	method := strings.ToUpper(item.Method)
+	if method == "" {
+		method = http.MethodGet
+	}
+	var transport graphql.Transport
+	switch method {
+	case http.MethodGet:
+		transport = GET{Mapping: mapping, Tracer: h.Tracer}
+	case http.MethodPost, http.MethodPut, http.MethodPatch:
+		transport = POST{Mapping: mapping, Tracer: h.Tracer}
+	case http.MethodDelete:
+		transport = DELETE{Mapping: mapping, Tracer: h.Tracer}
+	default:
+		return fail(http.StatusMethodNotAllowed, "method not allowed: "+method)
+	}
+
+	target, err := url.Parse(item.Path)
+	if err != nil {
+		return fail(http.StatusBadRequest, "invalid path: "+err.Error())
+	}
+	pattern, values, ok := mapping.matchRoute(method, target.Path)
+	if !ok {
+		return fail(http.StatusNotFound, "unknown route: "+method+" "+target.Path)
+	}
+	if mapping.isSubscription(method + ":" + pattern) {
+		return fail(http.StatusBadRequest, "subscriptions cannot be batched: "+target.Path)
+	}
+
+	query := target.Query()
+	for k, v := range item.Query {
+		switch v := v.(type) {
+		case nil:
+		case []interface{}:
+			for _, e := range v {
+				query.Add(k, fmt.Sprint(e))
+			}
+		default:
+			query.Add(k, fmt.Sprint(v))
+		}
+	}
+	target.RawQuery = query.Encode()
+
+	ctx := context.WithValue(r.Context(), routeContextKey, &boundRoute{pattern: pattern, values: values})
+	req, err := http.NewRequestWithContext(ctx, method, target.String(), bytes.NewReader(item.Body))
+	if err != nil {
+		return fail(http.StatusBadRequest, "invalid request: "+err.Error())
+	}
+	// the items are authorized like the batch request, and always answered in JSON
+	req.Header = r.Header.Clone()
+	req.Header.Del("Accept")
+	req.Header.Del("Content-Length")
+	// the items are executed without the idempotency of their routes, see Mapping.idempotent,
+	// so the idempotency key of the batch request is not passed on to them
+	req.Header.Del(IdempotencyKeyHeader)
+	req.Header.Set("Content-Type", "application/json")
+
+	transport.Do(w, req, exec)
+	return w.result()
+}
+
+// batchResponseWriter records the response to one REST call of a batch request
+type batchResponseWriter struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (w *batchResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *batchResponseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+func (w *batchResponseWriter) Write(b []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+	return w.body.Write(b)
+}
+
+func (w *batchResponseWriter) result() *BatchResult {
+	status := w.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	body, _ := ioutil.ReadAll(&w.body)
+	return &BatchResult{Status: status, Body: body}
+}
@@ -0,0 +1,154 @@
+package handlerx
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchPattern(t *testing.T) {
+	tests := []struct {
+		Pattern  string
+		Path     string
+		Expected []string
+		Literals int
+		OK       bool
+	}{
+		{"/todos", "/todos", []string{}, 1, true},
+		{"/todos/{id}", "/todos/T1", []string{"T1"}, 1, true},
+		{"/todos/{id}", "/todos/", nil, 0, false},
+		{"/todos/{id}", "/todos/T1/tags", nil, 0, false},
+		{"/todos/{id:[0-9]+}", "/todos/12", []string{"12"}, 1, true},
+		{"/todos/{id:[0-9]+}", "/todos/T1", nil, 0, false},
+		{"/files/{path...}", "/files/a/b.txt", []string{"a/b.txt"}, 1, true},
+		{"/files/*", "/files/a/b.txt", []string{}, 1, true},
+		{"/todos/{$}", "/todos/", []string{}, 1, true},
+		{"/todos/{$}", "/todos/T1", nil, 0, false},
+		{"/todos/{id:[0-9}", "/todos/12", nil, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Pattern+" "+tt.Path, func(t *testing.T) {
+			values, literals, ok := compileRoutePattern("GET:" + tt.Pattern).match(tt.Path)
+			assert.Equal(t, tt.OK, ok)
+			assert.Equal(t, tt.Expected, values)
+			assert.Equal(t, tt.Literals, literals)
+		})
+	}
+
+	t.Run("literal segments win", func(t *testing.T) {
+		m := newTestSubscriptionMapping()
+		m.operations["GET:/todos/done"] = "todos"
+		m.buildQueries()
+
+		pattern, values, ok := m.matchRoute("GET", "/todos/done")
+		assert.True(t, ok)
+		assert.Equal(t, "/todos/done", pattern)
+		assert.Empty(t, values)
+
+		pattern, values, ok = m.matchRoute("GET", "/todos/T1")
+		assert.True(t, ok)
+		assert.Equal(t, "/todos/{id}", pattern)
+		assert.Equal(t, []string{"T1"}, values)
+	})
+}
+
+func TestBatch(t *testing.T) {
+	h := newTestRouter(NewPreparedQueryCache(nil))
+
+	t.Run("results in order", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/batch", strings.NewReader(`[
+			{"method": "GET", "path": "/todos", "query": {"limit": 10, "ids": ["T1", "T2"]}},
+			{"method": "GET", "path": "/todos/T9"},
+			{"method": "POST", "path": "/todos", "body": {"input": {"text": "buy milk", "userId": "U1"}}},
+			{"method": "DELETE", "path": "/todos/T1"},
+			{"method": "GET", "path": "/todos/missing"},
+			{"method": "GET", "path": "/users"},
+			{"method": "HEAD", "path": "/todos"},
+			{"path": "/todos?done=maybe"}
+		]`))
+		r.Header.Set("Content-Type", "application/json")
+		h.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var results []*BatchResult
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &results))
+		require.Len(t, results, 8)
+
+		expected := []struct {
+			Status int
+			Body   string
+		}{
+			{http.StatusOK, `{"code":0,"data":[{"done":false,"id":"T1","text":"buy milk"},{"done":true,"id":"T2","text":"walk dog"}],"total":2,"pagination":{"limit":10}}`},
+			{http.StatusOK, `{"code":0,"data":{"done":false,"id":"T9","text":"buy milk"}}`},
+			{http.StatusOK, `{"code":0,"data":{"done":false,"id":"T3","text":"buy milk"}}`},
+			{http.StatusOK, `{"code":0,"data":true}`},
+			{http.StatusInternalServerError, `{"code":500,"message":"todo not found","request_id":"test-request-id","data":null}`},
+			{http.StatusNotFound, `{"code":404,"message":"unknown route: GET /users","request_id":"test-request-id","data":null}`},
+			{http.StatusMethodNotAllowed, `{"code":405,"message":"method not allowed: HEAD","request_id":"test-request-id","data":null}`},
+		}
+		for i, e := range expected {
+			assert.Equal(t, e.Status, results[i].Status, "item %d", i)
+			assert.JSONEq(t, e.Body, string(results[i].Body), "item %d", i)
+		}
+		assert.Equal(t, http.StatusBadRequest, results[7].Status)
+	})
+
+	t.Run("invalid body", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/batch", strings.NewReader(`{"method": "GET"}`))
+		h.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "batch body could not be decoded")
+	})
+
+	t.Run("OPTIONS", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("OPTIONS", "/batch", nil))
+
+		assert.Equal(t, "POST, OPTIONS", w.Header().Get("Allow"))
+	})
+}
+
+func TestBatchLimits(t *testing.T) {
+	m := newTestMapping()
+	require.NoError(t, m.Prepare(testSchema))
+	srv := handler.New(newTestExecutableSchema())
+	srv.AddTransport(Batch{MaxItems: 2, MaxBodySize: 256})
+	h := chi.NewRouter()
+	m.BindBatch(ChiBinder{Router: h}, srv, "/batch")
+
+	batch := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/batch", strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/json")
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	t.Run("within the limits", func(t *testing.T) {
+		w := batch(`[{"path": "/todos/T1"}, {"path": "/todos/T2"}]`)
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("too many items", func(t *testing.T) {
+		w := batch(`[{"path": "/todos/T1"}, {"path": "/todos/T2"}, {"path": "/todos/T3"}]`)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+		assert.Contains(t, w.Body.String(), "batch has 3 items, at most 2 are accepted")
+	})
+
+	t.Run("body too large", func(t *testing.T) {
+		w := batch(`[{"path": "/todos/T1", "body": {"text": "` + strings.Repeat("x", 256) + `"}}]`)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+		assert.Contains(t, w.Body.String(), "batch body is larger than 256 bytes")
+	})
+}
@@ -0,0 +1,104 @@
+package handlerx
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// RouteBinder mounts REST routes on a router, and reads the path parameters of the requests
+// it routes. Adapters are provided for chi and http.ServeMux, other routers only need the
+// two methods, eg. for gorilla/mux:
+//
+//	type MuxBinder struct{ Router *mux.Router }
+//
+//	func (b MuxBinder) Bind(method string, pattern string, handler http.Handler) {
+//		b.Router.Handle(pattern, handler).Methods(method)
+//	}
+//
+//	func (b MuxBinder) PathValue(r *http.Request, name string) string {
+//		return mux.Vars(r)[name]
+//	}
+type RouteBinder interface {
+	// Bind mounts handler for the method and the route pattern, eg. "/todos/{id}"
+	Bind(method string, pattern string, handler http.Handler)
+	// PathValue returns the value of a path parameter of a request routed by a bound route
+	PathValue(r *http.Request, name string) string
+}
+
+// ChiBinder mounts REST routes on a chi router
+type ChiBinder struct {
+	Router chi.Router
+}
+
+var _ RouteBinder = ChiBinder{}
+
+func (b ChiBinder) Bind(method string, pattern string, handler http.Handler) {
+	b.Router.Method(method, pattern, handler)
+}
+
+func (b ChiBinder) PathValue(r *http.Request, name string) string {
+	return chi.URLParam(r, name)
+}
+
+// boundRoute is the REST route matched by a request, it is set by Mapping.Bind, or with the
+// path parameters already resolved by the batch route
+type boundRoute struct {
+	pattern string
+	binder  RouteBinder
+	values  []string
+}
+
+type routeContextType string
+
+var routeContextKey routeContextType = "gogqlrest_route"
+
+func withBoundRoute(pattern string, binder RouteBinder, next http.Handler) http.Handler {
+	route := &boundRoute{pattern: pattern, binder: binder}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), routeContextKey, route)))
+	})
+}
+
+// requestRoute returns the route pattern and the path parameters of a REST request. Routes
+// mounted without Mapping.Bind are looked up in the chi routing context.
+func requestRoute(r *http.Request) (string, []string, []string) {
+	if route, ok := r.Context().Value(routeContextKey).(*boundRoute); ok {
+		keys := patternParams(route.pattern)
+		if route.binder == nil {
+			return route.pattern, keys, route.values
+		}
+		values := make([]string, 0, len(keys))
+		for _, k := range keys {
+			values = append(values, route.binder.PathValue(r, k))
+		}
+		return route.pattern, keys, values
+	}
+
+	if rctx := chi.RouteContext(r.Context()); rctx != nil {
+		return rctx.RoutePattern(), rctx.URLParams.Keys, rctx.URLParams.Values
+	}
+	return "", nil, nil
+}
+
+// patternParams returns the names of the path parameters of a route pattern, both the chi
+// and the http.ServeMux forms are accepted, eg. "{id}", "{id:[0-9]+}" or "{path...}"
+func patternParams(pattern string) []string {
+	names := make([]string, 0)
+	for _, segment := range strings.Split(pattern, "/") {
+		if !strings.HasPrefix(segment, "{") || !strings.HasSuffix(segment, "}") {
+			continue
+		}
+		name := strings.TrimSuffix(segment[1:len(segment)-1], "...")
+		if i := strings.Index(name, ":"); i >= 0 {
+			name = name[:i]
+		}
+		if name == "" || name == "$" {
+			continue
+		}
+		names = append(names, name)
+	}
+	return names
+}
@@ -0,0 +1,24 @@
+//go:build go1.22
+
+package handlerx
+
+import (
+	"net/http"
+)
+
+// ServeMuxBinder mounts REST routes on a http.ServeMux, using the method and wildcard
+// patterns of Go 1.22, eg. "GET /todos/{id}". The main module must declare go 1.22 or later,
+// otherwise http.ServeMux keeps its old patterns unless GODEBUG=httpmuxgo121=0 is set.
+type ServeMuxBinder struct {
+	Mux *http.ServeMux
+}
+
+var _ RouteBinder = ServeMuxBinder{}
+
+func (b ServeMuxBinder) Bind(method string, pattern string, handler http.Handler) {
+	b.Mux.Handle(method+" "+pattern, handler)
+}
+
+func (b ServeMuxBinder) PathValue(r *http.Request, name string) string {
+	return r.PathValue(name)
+}
@@ -0,0 +1,70 @@
+//go:build go1.22
+
+//go:debug httpmuxgo121=0
+
+package handlerx
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServeMuxBinder(t *testing.T) {
+	m := newTestMapping()
+	require.NoError(t, m.Prepare(testSchema))
+
+	srv := handler.New(newTestExecutableSchema())
+	srv.AddTransport(GET{})
+	srv.AddTransport(POST{})
+	srv.AddTransport(DELETE{})
+	srv.SetQueryCache(NewPreparedQueryCache(nil))
+
+	mux := http.NewServeMux()
+	m.Bind(ServeMuxBinder{Mux: mux}, srv)
+
+	tests := []struct {
+		Name     string
+		Method   string
+		Target   string
+		Body     string
+		Expected string
+	}{
+		{
+			Name:     "GET path parameter",
+			Method:   "GET",
+			Target:   "/todos/T9",
+			Expected: `{"code":0,"data":{"done":false,"id":"T9","text":"buy milk"}}`,
+		},
+		{
+			Name:     "POST input",
+			Method:   "POST",
+			Target:   "/todos",
+			Body:     `{"input":{"text":"buy milk","userId":"U1"}}`,
+			Expected: `{"code":0,"data":{"done":false,"id":"T3","text":"buy milk"}}`,
+		},
+		{
+			Name:     "DELETE",
+			Method:   "DELETE",
+			Target:   "/todos/T1",
+			Expected: `{"code":0,"data":true}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(tt.Method, tt.Target, strings.NewReader(tt.Body))
+			r.Header.Set("Content-Type", "application/json")
+			mux.ServeHTTP(w, r)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.JSONEq(t, tt.Expected, w.Body.String())
+		})
+	}
+}
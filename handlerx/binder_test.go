@@ -0,0 +1,26 @@
+package handlerx
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPatternParams(t *testing.T) {
+	tests := []struct {
+		Pattern  string
+		Expected []string
+	}{
+		{Pattern: "/todos", Expected: []string{}},
+		{Pattern: "/todos/{id}", Expected: []string{"id"}},
+		{Pattern: "/users/{userId}/todos/{id:[0-9]+}", Expected: []string{"userId", "id"}},
+		{Pattern: "/files/{path...}", Expected: []string{"path"}},
+		{Pattern: "/todos/{$}", Expected: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Pattern, func(t *testing.T) {
+			assert.Equal(t, tt.Expected, patternParams(tt.Pattern))
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package handlerx
+
+import (
+	"context"
+
+	"github.com/99designs/gqlgen/graphql"
+)
+
+// preparedQueryCache serves the query documents prepared by Mapping.Prepare for the mapping
+// of the request, and falls back to the wrapped cache for any other GraphQL query.
+type preparedQueryCache struct {
+	graphql.Cache
+}
+
+// NewPreparedQueryCache wraps a query cache so that REST requests reuse the documents
+// parsed and validated at startup. It is installed by NewDefaultServer.
+func NewPreparedQueryCache(cache graphql.Cache) graphql.Cache {
+	if cache == nil {
+		cache = graphql.NoCache{}
+	}
+	return preparedQueryCache{Cache: cache}
+}
+
+func (c preparedQueryCache) Get(ctx context.Context, key string) (interface{}, bool) {
+	if m := GetMapping(ctx); m != nil {
+		if doc, ok := m.preparedDocument(key); ok {
+			return doc, true
+		}
+	}
+	return c.Cache.Get(ctx, key)
+}
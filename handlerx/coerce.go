@@ -0,0 +1,185 @@
+package handlerx
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/speedoops/go-gqlrest/scalar"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// Locations of REST parameters
+const (
+	paramInPath  = "path"
+	paramInQuery = "query"
+	paramInBody  = "body"
+)
+
+// paramError is a REST parameter whose value does not have its declared type, eg. "?count=abc"
+type paramError struct {
+	// path of the value in the GraphQL variables, eg. ["input", "network", "vlan"]
+	path     []string
+	name     string
+	in       string
+	expected string
+	value    interface{}
+	reason   string
+}
+
+func (e *paramError) Error() string {
+	msg := fmt.Sprintf("%s parameter %s: expected %s, found %s", e.in, e.name, e.expected, describeValue(e.value))
+	if e.reason != "" {
+		msg += " (" + e.reason + ")"
+	}
+	return msg
+}
+
+// paramErrors are the parameter errors of a request, each is reported as an error of the response
+type paramErrors []*paramError
+
+func (errs paramErrors) Error() string {
+	msgs := make([]string, 0, len(errs))
+	for _, e := range errs {
+		msgs = append(msgs, e.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// gqlErrors returns the errors of the response, with the code, the name and the location of the parameters
+func (errs paramErrors) gqlErrors(code int) gqlerror.List {
+	list := make(gqlerror.List, 0, len(errs))
+	for _, e := range errs {
+		list = append(list, &gqlerror.Error{
+			Message: e.Error(),
+			Extensions: map[string]interface{}{
+				"code":  strconv.Itoa(code),
+				"param": e.name,
+				"in":    e.in,
+				"type":  e.expected,
+			},
+		})
+	}
+	return list
+}
+
+// newParamError returns the error of a value of a parameter, the name and the location
+// are set by the caller, see paramSource
+func newParamError(name string, expected string, value interface{}, reason string) *paramError {
+	return &paramError{path: []string{name}, expected: expected, value: value, reason: reason}
+}
+
+// asParamErrors returns the parameter errors of err, prefixing their paths with the name of
+// the parameter or input field holding them
+func asParamErrors(err error, prefix string) (paramErrors, bool) {
+	var errs paramErrors
+	var e *paramError
+	switch {
+	case errors.As(err, &errs):
+	case errors.As(err, &e):
+		errs = paramErrors{e}
+	default:
+		return nil, false
+	}
+
+	for _, e := range errs {
+		if prefix != "" {
+			e.path = append([]string{prefix}, e.path...)
+		}
+	}
+	return errs, true
+}
+
+// paramSource is the name and the location of a parameter as sent by the client
+type paramSource struct {
+	name string
+	in   string
+}
+
+// locate sets the names and the locations of the errors from the sources of the parameters and of the input fields,
+// and sorts them by name
+func (errs paramErrors) locate(sources map[string]paramSource, inputSources map[string]paramSource) paramErrors {
+	seen := make(map[string]bool, len(errs))
+	located := make(paramErrors, 0, len(errs))
+	for _, e := range errs {
+		source, path := sources[e.path[0]], e.path[1:]
+		if e.path[0] == "input" && len(e.path) > 1 {
+			if inputSource, ok := inputSources[e.path[1]]; ok {
+				source, path = inputSource, e.path[2:]
+			}
+		}
+		if source.in == "" {
+			source = paramSource{name: e.path[0], in: paramInBody}
+		}
+
+		e.name, e.in = source.name, source.in
+		for _, p := range path {
+			if strings.HasPrefix(p, "[") {
+				e.name += p
+			} else {
+				e.name += "." + p
+			}
+		}
+		// the query and path parameters are both arguments and input fields
+		if !seen[e.in+":"+e.name] {
+			seen[e.in+":"+e.name] = true
+			located = append(located, e)
+		}
+	}
+
+	sort.SliceStable(located, func(i, j int) bool { return located[i].name < located[j].name })
+	return located
+}
+
+// describeValue formats a parameter value for an error message, eg. `"abc"` or `1.5`
+func describeValue(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return strconv.Quote(v)
+	case map[string]interface{}:
+		return "an object"
+	case []interface{}:
+		return "a list"
+	case nil:
+		return "null"
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+// RegisterScalar registers how the REST values of a scalar are converted, or replaces a built-in scalar,
+// see the scalar package. The other scalars of the schema are strings.
+func (m *Mapping) RegisterScalar(s scalar.Scalar) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.scalars == nil {
+		m.scalars = scalar.NewRegistry()
+	}
+	m.scalars.Register(s)
+}
+
+// WithScalar registers a scalar when the mapping is created, eg.
+// RegisterHandlers(binder, srv, "", handlerx.WithScalar(scalar.Scalar{Name: "UUID", Format: "uuid"}))
+func WithScalar(s scalar.Scalar) MappingOption {
+	return func(m *Mapping) {
+		m.RegisterScalar(s)
+	}
+}
+
+// scalar returns the scalar of a type: the registered scalar, or a string scalar for the other
+// scalars of the schema, eg. "UUID"
+func (m *Mapping) scalar(typeName string) (*scalar.Scalar, bool) {
+	kind, ok := m.typeKinds[typeName]
+	if ok && kind != "SCALAR" {
+		return nil, false
+	}
+	if s, ok := m.scalars.Lookup(typeName); ok {
+		return s, true
+	}
+	if ok {
+		return scalar.Default(typeName), true
+	}
+	return nil, false
+}
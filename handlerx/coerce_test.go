@@ -0,0 +1,113 @@
+package handlerx
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/speedoops/go-gqlrest/scalar"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCustomScalars(t *testing.T) {
+	m := NewMapping(WithScalar(scalar.Scalar{Name: "UUID", Format: "uuid", Pattern: "^[0-9a-f]{8}(-[0-9a-f]{4}){3}-[0-9a-f]{12}$"}))
+	m.Setup(StringMap{}, StringMap{}, ArgTypeMap{}, ArgTypeMap{}, StringMap{
+		"Color":    "SCALAR",
+		"UUID":     "SCALAR",
+		"Duration": "SCALAR",
+		"IP":       "ENUM",
+	})
+	m.RegisterScalar(scalar.Scalar{Name: "Duration", Type: scalar.TypeInteger, Format: "int64"})
+
+	tests := []struct {
+		Type     string
+		Value    interface{}
+		Expected interface{}
+		Error    string
+	}{
+		{"Color", "red", "red", ""},
+		{"Color", json.Number("1"), "1", ""},
+		{"UUID", "123e4567-e89b-12d3-a456-426614174000", "123e4567-e89b-12d3-a456-426614174000", ""},
+		{"UUID", "123", nil, `query parameter id: expected UUID, found "123" (must match ^[0-9a-f]{8}(-[0-9a-f]{4}){3}-[0-9a-f]{12}$)`},
+		{"Duration", "10", json.Number("10"), ""},
+		{"Duration", "10s", nil, `query parameter id: expected Duration, found "10s"`},
+		{"Time", "2022-07-01", nil, `query parameter id: expected Time, found "2022-07-01" (expected RFC 3339)`},
+		{"IP", "10.0.0.1", "10.0.0.1", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Type, func(t *testing.T) {
+			v, err := m.formatArgValueToGraphQL(tt.Type, "id", tt.Value)
+			if tt.Error == "" {
+				require.NoError(t, err)
+				assert.Equal(t, tt.Expected, v)
+				return
+			}
+			errs, ok := asParamErrors(err, "")
+			require.True(t, ok)
+			errs = errs.locate(map[string]paramSource{"id": {"id", paramInQuery}}, nil)
+			assert.EqualError(t, errs, tt.Error)
+		})
+	}
+
+	_, err := m.formatArgValueToGraphQL("Unknown", "id", "x")
+	assert.EqualError(t, err, `mapping: unknown argument type "x"`)
+
+	// the scalars are registered per mapping
+	other := NewMapping()
+	other.Setup(StringMap{}, StringMap{}, ArgTypeMap{}, ArgTypeMap{}, StringMap{"UUID": "SCALAR"})
+	v, err := other.formatArgValueToGraphQL("UUID", "id", "123")
+	assert.NoError(t, err)
+	assert.Equal(t, "123", v)
+}
+
+func TestParamErrors(t *testing.T) {
+	h := newTestRouter(NewPreparedQueryCache(nil))
+
+	tests := []struct {
+		Name     string
+		Method   string
+		Target   string
+		Body     string
+		Expected string
+	}{
+		{
+			Name: "query", Method: "GET", Target: "/todos?done=maybe&state=",
+			Expected: `query parameter done: expected Boolean, found "maybe"; query parameter state: expected TodoState, found ""`,
+		},
+		{
+			Name: "body", Method: "PUT", Target: "/hosts/H1",
+			Body:     `{"input":{"network":{"vlan":"ten"},"disks":[{"name":"sda","size":1.5}],"tags":"a"}}`,
+			Expected: `body parameter input.disks[0].size: expected Int, found 1.5; body parameter input.network.vlan: expected Int, found "ten"`,
+		},
+		{
+			Name: "query into input", Method: "POST", Target: "/todos?priority=high",
+			Body:     `{"input":{"text":"buy milk","userId":"U1"}}`,
+			Expected: `query parameter priority: expected Int, found "high"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(tt.Method, tt.Target, strings.NewReader(tt.Body))
+			r.Header.Set("Content-Type", "application/json")
+			h.ServeHTTP(w, r)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			var resp RESTResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			assert.Equal(t, http.StatusBadRequest, resp.Code)
+			assert.Equal(t, tt.Expected, resp.Message)
+		})
+	}
+
+	t.Run("bare flag", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", "/todos?done", nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+}
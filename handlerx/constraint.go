@@ -0,0 +1,183 @@
+package handlerx
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/errcode"
+	"github.com/speedoops/go-gqlrest/constraint"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// ConstraintValidator enforces the @constraintNumber, @constraintString, @constraintSlice and
+// @constraintStringSlice directives of the arguments and the input fields of the schema, with the
+// rules documented by restgen, for GraphQL and REST requests alike. It is installed by NewDefaultServer
+// with WithConstraintFormats, the formats of the @constraintString directives being the ones embedded
+// in the generated code. The directives with an unknown format are logged, and their format is not checked.
+//
+// A field whose arguments break a rule is not resolved, each violation is reported as an error with
+// the code GRAPHQL_VALIDATION_FAILED, ie. 422 for REST requests, and the extensions:
+//
+//	{"code": "GRAPHQL_VALIDATION_FAILED", "field": "input.name", "constraint": "maxLength"}
+type ConstraintValidator struct {
+	// Formats are the formats of validator.yaml, eg. the generated ConstraintFormats
+	Formats constraint.Formats
+
+	// Object.field => Argument => Rule, for the fields with constrained arguments
+	fields map[string]map[string]*constraint.Rule
+	// Input.field => Rule
+	inputFields map[string]*constraint.Rule
+	schema      *ast.Schema
+}
+
+var _ interface {
+	graphql.HandlerExtension
+	graphql.FieldInterceptor
+} = &ConstraintValidator{}
+
+func (v *ConstraintValidator) ExtensionName() string {
+	return "ConstraintValidator"
+}
+
+// Validate reads the rules of the schema once, when the extension is added to the server
+func (v *ConstraintValidator) Validate(schema graphql.ExecutableSchema) error {
+	v.schema = schema.Schema()
+	v.fields = make(map[string]map[string]*constraint.Rule)
+	v.inputFields = make(map[string]*constraint.Rule)
+
+	unknown := make([]string, 0)
+	for _, def := range v.schema.Types {
+		switch def.Kind {
+		case ast.Object, ast.Interface:
+			for _, field := range def.Fields {
+				for _, arg := range field.Arguments {
+					if !v.isConstrained(arg.Type, arg.Directives, nil) {
+						continue
+					}
+					if v.fields[def.Name+"."+field.Name] == nil {
+						v.fields[def.Name+"."+field.Name] = make(map[string]*constraint.Rule)
+					}
+					rule := constraint.ForDirectives(arg.Name, arg.Directives, v.Formats)
+					if rule != nil && rule.UnknownFormat != "" {
+						unknown = append(unknown, fmt.Sprintf("%q of %s.%s(%s)", rule.UnknownFormat, def.Name, field.Name, arg.Name))
+					}
+					v.fields[def.Name+"."+field.Name][arg.Name] = rule
+				}
+			}
+		case ast.InputObject:
+			for _, field := range def.Fields {
+				if rule := constraint.ForDirectives(field.Name, field.Directives, v.Formats); rule != nil {
+					if rule.UnknownFormat != "" {
+						unknown = append(unknown, fmt.Sprintf("%q of %s.%s", rule.UnknownFormat, def.Name, field.Name))
+					}
+					v.inputFields[def.Name+"."+field.Name] = rule
+				}
+			}
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		log.Printf("WARNING: constraint validator: unknown formats %s are not checked, see handlerx.WithConstraintFormats",
+			strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+// isConstrained reports whether a value of the type with the directives may break a rule
+func (v *ConstraintValidator) isConstrained(typ *ast.Type, directives ast.DirectiveList, visited map[string]bool) bool {
+	if constraint.Has(directives) {
+		return true
+	}
+
+	def := v.schema.Types[typ.Name()]
+	if def == nil || def.Kind != ast.InputObject || visited[def.Name] {
+		return false
+	}
+	if visited == nil {
+		visited = make(map[string]bool)
+	}
+	visited[def.Name] = true
+	for _, field := range def.Fields {
+		if v.isConstrained(field.Type, field.Directives, visited) {
+			return true
+		}
+	}
+	return false
+}
+
+func (v *ConstraintValidator) InterceptField(ctx context.Context, next graphql.Resolver) (interface{}, error) {
+	fc := graphql.GetFieldContext(ctx)
+	if fc == nil || fc.Field.Field == nil || fc.Field.Definition == nil {
+		return next(ctx)
+	}
+	rules, ok := v.fields[fc.Object+"."+fc.Field.Name]
+	if !ok {
+		return next(ctx)
+	}
+
+	var variables map[string]interface{}
+	if graphql.HasOperationContext(ctx) {
+		variables = graphql.GetOperationContext(ctx).Variables
+	}
+	values := fc.Field.ArgumentMap(variables)
+
+	errs := gqlerror.List{}
+	for _, arg := range fc.Field.Definition.Arguments {
+		if _, ok := rules[arg.Name]; !ok {
+			continue
+		}
+		errs = append(errs, v.check(ctx, arg.Name, values[arg.Name], arg.Type, rules[arg.Name])...)
+	}
+	if len(errs) == 0 {
+		return next(ctx)
+	}
+
+	for _, err := range errs {
+		graphql.AddError(ctx, err)
+	}
+	return nil, nil
+}
+
+// check returns the errors of a value of a type, against its rule and the rules of its input fields
+func (v *ConstraintValidator) check(ctx context.Context, path string, value interface{}, typ *ast.Type, rule *constraint.Rule) gqlerror.List {
+	if value == nil {
+		return nil
+	}
+
+	errs := gqlerror.List{}
+	if rule != nil {
+		for _, violation := range rule.Check(value) {
+			err := gqlerror.Errorf("%s: %s", path, violation.Message)
+			err.Path = graphql.GetPath(ctx)
+			errcode.Set(err, errcode.ValidationFailed)
+			err.Extensions["field"] = path
+			err.Extensions["constraint"] = violation.Constraint
+			errs = append(errs, err)
+		}
+	}
+
+	if typ.Elem != nil {
+		if list, ok := value.([]interface{}); ok {
+			for i, item := range list {
+				errs = append(errs, v.check(ctx, path+"["+strconv.Itoa(i)+"]", item, typ.Elem, nil)...)
+			}
+		}
+		return errs
+	}
+
+	def := v.schema.Types[typ.Name()]
+	if obj, ok := value.(map[string]interface{}); ok && def != nil && def.Kind == ast.InputObject {
+		for _, field := range def.Fields {
+			if fieldValue, ok := obj[field.Name]; ok {
+				errs = append(errs, v.check(ctx, path+"."+field.Name, fieldValue, field.Type, v.inputFields[def.Name+"."+field.Name])...)
+			}
+		}
+	}
+	return errs
+}
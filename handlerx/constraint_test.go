@@ -0,0 +1,211 @@
+package handlerx
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+	"github.com/go-chi/chi/v5"
+	"github.com/speedoops/go-gqlrest/constraint"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vektah/gqlparser/v2"
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+var testConstraintSchema = gqlparser.MustLoadSchema(&ast.Source{Input: `
+	directive @constraintNumber(min: Float, max: Float) on ARGUMENT_DEFINITION | INPUT_FIELD_DEFINITION
+	directive @constraintString(minLength: Int, maxLength: Int, format: String) on ARGUMENT_DEFINITION | INPUT_FIELD_DEFINITION
+	directive @constraintSlice(maxItems: Int) on ARGUMENT_DEFINITION | INPUT_FIELD_DEFINITION
+	type Todo {
+		id: ID!
+		text: String!
+	}
+	input NewTodoInput {
+		text: String! @constraintString(minLength: 1, maxLength: 8)
+		tags: [String!] @constraintSlice(maxItems: 2)
+		priority: Int @constraintNumber(min: 1, max: 5)
+		owner: String @constraintString(format: "email")
+	}
+	type Query {
+		todos(limit: Int @constraintNumber(min: 1, max: 100)): [Todo!]!
+	}
+	type Mutation {
+		createTodo(input: NewTodoInput!): Todo!
+	}
+`})
+
+var testConstraintFormats = constraint.Formats{
+	"email": {Pattern: `^[^@\s]+@[^@\s]+$`},
+}
+
+// newTestConstraintServer resolves the root field through the field middlewares, like the generated code
+func newTestConstraintServer() *handler.Server {
+	es := &graphql.ExecutableSchemaMock{
+		ExecFunc: func(ctx context.Context) graphql.ResponseHandler {
+			rc := graphql.GetOperationContext(ctx)
+			field := rc.Operation.SelectionSet[0].(*ast.Field)
+			object := testConstraintSchema.Query.Name
+			if rc.Operation.Operation == ast.Mutation {
+				object = testConstraintSchema.Mutation.Name
+			}
+
+			fc := &graphql.FieldContext{Object: object, Field: graphql.CollectedField{Field: field}}
+			res, _ := rc.ResolverMiddleware(graphql.WithFieldContext(ctx, fc), func(ctx context.Context) (interface{}, error) {
+				if field.Name == "todos" {
+					return []map[string]interface{}{{"id": "T1", "text": "buy milk"}}, nil
+				}
+				return map[string]interface{}{"id": "T2", "text": "walk dog"}, nil
+			})
+
+			if errs := graphql.GetErrors(ctx); len(errs) > 0 {
+				return graphql.OneShot(&graphql.Response{Errors: errs, Data: []byte(`null`)})
+			}
+			b, _ := json.Marshal(map[string]interface{}{field.Name: res})
+			return graphql.OneShot(&graphql.Response{Data: b})
+		},
+		SchemaFunc: func() *ast.Schema {
+			return testConstraintSchema
+		},
+		ComplexityFunc: func(typeName, fieldName string, childComplexity int, args map[string]interface{}) (int, bool) {
+			return 0, false
+		},
+	}
+
+	srv := handler.New(es)
+	srv.Use(&ConstraintValidator{Formats: testConstraintFormats})
+	return srv
+}
+
+func TestConstraintValidator(t *testing.T) {
+	m := NewMapping()
+	m.Setup(
+		StringMap{
+			"GET:/todos":  "todos",
+			"POST:/todos": "createTodo",
+		},
+		StringMap{
+			"todos":      "{id,text}",
+			"createTodo": "{id,text}",
+		},
+		ArgTypeMap{
+			"todos":      {"limit": "Int"},
+			"createTodo": {"input": "NewTodoInput!"},
+		},
+		ArgTypeMap{
+			"NewTodoInput": {"text": "String!", "tags": "[String!]", "priority": "Int", "owner": "String"},
+		},
+		StringMap{
+			"NewTodoInput": "INPUT_OBJECT",
+		},
+	)
+	require.NoError(t, m.Prepare(testConstraintSchema))
+
+	srv := newTestConstraintServer()
+	srv.AddTransport(GET{})
+	srv.AddTransport(POST{})
+	r := chi.NewRouter()
+	m.Bind(ChiBinder{Router: r}, srv)
+
+	t.Run("REST query", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", "/todos?limit=1000", nil))
+
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+		assert.JSONEq(t, `{"code":422,"message":"limit: must be at most 100 todos","data":null,"request_id":"test-request-id"}`, w.Body.String())
+	})
+
+	t.Run("REST mutation", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "/todos", strings.NewReader(`{"input":{"text":"buy oat milk","tags":["a","b","c"],"priority":3}}`))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+		assert.Contains(t, w.Body.String(), "input.text: must be at most 8 characters long")
+	})
+
+	t.Run("REST format", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "/todos", strings.NewReader(`{"input":{"text":"walk dog","owner":"nobody"}}`))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+		assert.Contains(t, w.Body.String(), "input.owner: must be a valid email")
+	})
+
+	t.Run("REST valid", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "/todos", strings.NewReader(`{"input":{"text":"walk dog","tags":["a"],"priority":5,"owner":"me@example.com"}}`))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"code":0,"data":{"id":"T2","text":"walk dog"}}`, w.Body.String())
+	})
+
+	t.Run("GraphQL", func(t *testing.T) {
+		gql := newTestConstraintServer()
+		gql.AddTransport(transport.POST{})
+
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "/query", strings.NewReader(`{
+			"query": "mutation($tags: [String!]) { createTodo(input: {text: \"walk dog\", tags: $tags, priority: 0}) { id } }",
+			"variables": {"tags": ["a", "b", "c"]}
+		}`))
+		req.Header.Set("Content-Type", "application/json")
+		gql.ServeHTTP(w, req)
+
+		var resp struct {
+			Errors []struct {
+				Message    string                 `json:"message"`
+				Path       []interface{}          `json:"path"`
+				Extensions map[string]interface{} `json:"extensions"`
+			} `json:"errors"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		require.Len(t, resp.Errors, 2)
+		assert.Equal(t, "input.tags: must have at most 2 items", resp.Errors[0].Message)
+		assert.Equal(t, []interface{}{"createTodo"}, resp.Errors[0].Path)
+		assert.Equal(t, map[string]interface{}{
+			"code": "GRAPHQL_VALIDATION_FAILED", "field": "input.tags", "constraint": "maxItems",
+		}, resp.Errors[0].Extensions)
+		assert.Equal(t, "input.priority: must be at least 1", resp.Errors[1].Message)
+	})
+}
+
+func TestConstraintValidatorUnknownFormat(t *testing.T) {
+	es := &graphql.ExecutableSchemaMock{
+		SchemaFunc: func() *ast.Schema {
+			return testConstraintSchema
+		},
+	}
+
+	// the unknown formats are logged and not checked, the server still starts
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	v := &ConstraintValidator{}
+	require.NoError(t, v.Validate(es))
+	assert.Contains(t, logs.String(), `"email" of NewTodoInput.owner`)
+	assert.Nil(t, v.inputFields["NewTodoInput.owner"].Pattern)
+
+	logs.Reset()
+	assert.NoError(t, (&ConstraintValidator{Formats: testConstraintFormats}).Validate(es))
+	assert.NotContains(t, logs.String(), "unknown formats")
+
+	// the validator is opt-in
+	assert.NotPanics(t, func() { NewDefaultServer(es) })
+	assert.NotPanics(t, func() { NewDefaultServer(es, WithConstraintFormats(nil)) })
+}
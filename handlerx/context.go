@@ -1,10 +1,33 @@
 package handlerx
 
-import "context"
+import (
+	"context"
+	"net/url"
+)
 
 type ResponseContext struct {
 	context.Context
 	total *int64
+	// HTTP status of successful REST responses, 0 means 200
+	status int
+	// HTTP status already written for an error response, 0 if not written
+	errorStatus int
+	// negotiated media type and encoder of REST responses, JSON if not set
+	mediaType string
+	encoder   Encoder
+
+	// paging input and output of paginated list routes
+	pagination *Pagination
+	nextCursor string
+	prevCursor string
+	url        *url.URL
+
+	// Last-Event-ID of a request to a subscription route
+	lastEventID string
+
+	// X-Request-ID of the request, and the codes of the errors of its response
+	requestID  string
+	errorCodes []string
 }
 
 type responseContextType string
@@ -30,3 +53,37 @@ func (c *ResponseContext) Total() *int64 {
 func (c *ResponseContext) SetTotal(total int64) {
 	c.total = &total
 }
+
+// Pagination returns the paging parameters of a paginated REST list route, or nil for other requests
+func (c *ResponseContext) Pagination() *Pagination {
+	return c.pagination
+}
+
+func (c *ResponseContext) NextCursor() string {
+	return c.nextCursor
+}
+
+// SetNextCursor reports the cursor of the next page, it is returned to REST callers as the `after` parameter
+func (c *ResponseContext) SetNextCursor(cursor string) {
+	c.nextCursor = cursor
+}
+
+func (c *ResponseContext) PrevCursor() string {
+	return c.prevCursor
+}
+
+// SetPrevCursor reports the cursor of the previous page, it is returned to REST callers as the `before` parameter
+func (c *ResponseContext) SetPrevCursor(cursor string) {
+	c.prevCursor = cursor
+}
+
+// LastEventID returns the Last-Event-ID header of a reconnecting client of a subscription route,
+// ie. the number of the last event it received, or "" for a new stream, see SSE
+func (c *ResponseContext) LastEventID() string {
+	return c.lastEventID
+}
+
+// RequestID returns the ID of the request, taken from or generated into its X-Request-ID header
+func (c *ResponseContext) RequestID() string {
+	return c.requestID
+}
@@ -0,0 +1,145 @@
+package handlerx
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORSPolicy is the cross-origin policy of the REST routes of a mapping, see
+// https://fetch.spec.whatwg.org/#http-cors-protocol
+type CORSPolicy struct {
+	// AllowedOrigins are the origins allowed to call the routes, "*" allows any origin
+	// but can not be used with AllowCredentials
+	AllowedOrigins []string
+	// AllowedHeaders are the request headers allowed in preflight requests, "*" allows any header
+	AllowedHeaders []string
+	// ExposedHeaders are the response headers readable by the callers
+	ExposedHeaders []string
+	// AllowCredentials allows requests with cookies or HTTP authentication
+	AllowCredentials bool
+	// MaxAge is how long the preflight response can be cached, zero leaves it to the browser
+	MaxAge time.Duration
+}
+
+// SetCORSPolicy sets the cross-origin policy of the REST routes, CORS is disabled if nil
+func (m *Mapping) SetCORSPolicy(policy *CORSPolicy) error {
+	if policy != nil && policy.AllowCredentials && contains(policy.AllowedOrigins, "*") {
+		// any website could read the responses of the credentialed requests of its visitors
+		return errors.New("cors: the wildcard origin \"*\" can not be allowed with credentials, list the allowed origins")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.cors = policy
+	return nil
+}
+
+func (m *Mapping) corsPolicy() *CORSPolicy {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.cors
+}
+
+// allowOrigin returns the Access-Control-Allow-Origin value for the origin, or "" if not allowed
+func (p *CORSPolicy) allowOrigin(origin string) string {
+	for _, allowed := range p.AllowedOrigins {
+		if allowed == "*" && !p.AllowCredentials {
+			return "*"
+		}
+		if strings.EqualFold(allowed, origin) {
+			return origin
+		}
+	}
+	return ""
+}
+
+// writeCORSHeaders adds the CORS headers to the response of an actual request, if its origin is allowed
+func (m *Mapping) writeCORSHeaders(w http.ResponseWriter, r *http.Request) {
+	policy := m.corsPolicy()
+	origin := r.Header.Get("Origin")
+	if policy == nil || origin == "" {
+		return
+	}
+	header := w.Header()
+	header.Add("Vary", "Origin")
+
+	allowOrigin := policy.allowOrigin(origin)
+	if allowOrigin == "" {
+		return
+	}
+	header.Set("Access-Control-Allow-Origin", allowOrigin)
+	if policy.AllowCredentials {
+		header.Set("Access-Control-Allow-Credentials", "true")
+	}
+	if len(policy.ExposedHeaders) > 0 {
+		header.Set("Access-Control-Expose-Headers", strings.Join(policy.ExposedHeaders, ", "))
+	}
+}
+
+// writePreflightHeaders answers a CORS preflight request with the methods of its route,
+// it returns false if the request is not a preflight request, or is not allowed by the policy
+func (m *Mapping) writePreflightHeaders(w http.ResponseWriter, r *http.Request, methods []string) bool {
+	policy := m.corsPolicy()
+	origin := r.Header.Get("Origin")
+	requestMethod := r.Header.Get("Access-Control-Request-Method")
+	if policy == nil || origin == "" || requestMethod == "" {
+		return false
+	}
+	header := w.Header()
+	header.Add("Vary", "Origin")
+	header.Add("Vary", "Access-Control-Request-Method")
+	header.Add("Vary", "Access-Control-Request-Headers")
+
+	allowOrigin := policy.allowOrigin(origin)
+	if allowOrigin == "" || !contains(methods, requestMethod) {
+		return false
+	}
+
+	allowHeaders := make([]string, 0)
+	for _, h := range strings.Split(r.Header.Get("Access-Control-Request-Headers"), ",") {
+		h = strings.TrimSpace(h)
+		if h == "" {
+			continue
+		}
+		if !contains(policy.AllowedHeaders, "*") && !containsFold(policy.AllowedHeaders, h) {
+			return false
+		}
+		allowHeaders = append(allowHeaders, h)
+	}
+
+	header.Set("Access-Control-Allow-Origin", allowOrigin)
+	header.Set("Access-Control-Allow-Methods", strings.Join(methods, ", "))
+	if len(allowHeaders) > 0 {
+		header.Set("Access-Control-Allow-Headers", strings.Join(allowHeaders, ", "))
+	}
+	if policy.AllowCredentials {
+		header.Set("Access-Control-Allow-Credentials", "true")
+	}
+	if policy.MaxAge > 0 {
+		header.Set("Access-Control-Max-Age", strconv.Itoa(int(policy.MaxAge/time.Second)))
+	}
+	return true
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,132 @@
+package handlerx
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql/handler/lru"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHeadAndOptions(t *testing.T) {
+	h := newTestRouter(NewPreparedQueryCache(nil))
+
+	t.Run("HEAD runs the GET route without body", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("HEAD", "/todos?limit=1", nil))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+		assert.Contains(t, w.Header().Get("Link"), `rel="next"`)
+		assert.Empty(t, w.Body.String())
+	})
+
+	t.Run("HEAD with fields", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("HEAD", "/todos/T1?fields=id", nil))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, w.Body.String())
+	})
+
+	tests := []struct {
+		Target   string
+		Expected string
+	}{
+		{Target: "/todos", Expected: "GET, HEAD, POST, OPTIONS"},
+		{Target: "/todos/T1", Expected: "GET, HEAD, DELETE, OPTIONS"},
+		{Target: "/hosts/H1", Expected: "PUT, OPTIONS"},
+	}
+	for _, tt := range tests {
+		t.Run("OPTIONS "+tt.Target, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest("OPTIONS", tt.Target, nil))
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, tt.Expected, w.Header().Get("Allow"))
+		})
+	}
+}
+
+func TestCORSPolicy(t *testing.T) {
+	m := newTestMapping()
+	require.NoError(t, m.SetCORSPolicy(&CORSPolicy{
+		AllowedOrigins:   []string{"https://app.example.com"},
+		AllowedHeaders:   []string{"Content-Type", "Authorization"},
+		ExposedHeaders:   []string{"Link"},
+		AllowCredentials: true,
+		MaxAge:           10 * time.Minute,
+	}))
+	h := newTestMappingRouter(m, NewPreparedQueryCache(lru.New(100)))
+
+	preflight := func(origin string, method string, headers string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("OPTIONS", "/todos/T1", nil)
+		r.Header.Set("Origin", origin)
+		r.Header.Set("Access-Control-Request-Method", method)
+		if headers != "" {
+			r.Header.Set("Access-Control-Request-Headers", headers)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	t.Run("allowed preflight", func(t *testing.T) {
+		w := preflight("https://app.example.com", "DELETE", "content-type, authorization")
+
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		assert.Equal(t, "https://app.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+		assert.Equal(t, "GET, HEAD, DELETE, OPTIONS", w.Header().Get("Access-Control-Allow-Methods"))
+		assert.Equal(t, "content-type, authorization", w.Header().Get("Access-Control-Allow-Headers"))
+		assert.Equal(t, "true", w.Header().Get("Access-Control-Allow-Credentials"))
+		assert.Equal(t, "600", w.Header().Get("Access-Control-Max-Age"))
+	})
+
+	t.Run("method not registered for the route", func(t *testing.T) {
+		w := preflight("https://app.example.com", "PUT", "")
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+	})
+
+	t.Run("origin not allowed", func(t *testing.T) {
+		w := preflight("https://evil.example.com", "GET", "")
+
+		assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+	})
+
+	t.Run("header not allowed", func(t *testing.T) {
+		w := preflight("https://app.example.com", "GET", "X-Custom")
+
+		assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+	})
+
+	t.Run("actual request", func(t *testing.T) {
+		r := httptest.NewRequest("GET", "/todos/T1", nil)
+		r.Header.Set("Origin", "https://app.example.com")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "https://app.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+		assert.Equal(t, "Link", w.Header().Get("Access-Control-Expose-Headers"))
+		assert.Equal(t, "Origin", w.Header().Get("Vary"))
+	})
+
+	t.Run("wildcard origin", func(t *testing.T) {
+		policy := &CORSPolicy{AllowedOrigins: []string{"*"}}
+		assert.Equal(t, "*", policy.allowOrigin("https://any.example.com"))
+		policy.AllowCredentials = true
+		assert.Empty(t, policy.allowOrigin("https://any.example.com"))
+	})
+
+	t.Run("wildcard origin with credentials", func(t *testing.T) {
+		m := newTestMapping()
+		err := m.SetCORSPolicy(&CORSPolicy{AllowedOrigins: []string{"*"}, AllowCredentials: true})
+		assert.Error(t, err)
+		assert.Nil(t, m.corsPolicy())
+	})
+}
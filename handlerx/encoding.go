@@ -0,0 +1,247 @@
+package handlerx
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Media types of the REST responses, the ones besides JSON, NDJSON and server-sent events have an encoder shipped with handlerx
+const (
+	MediaTypeJSON        = "application/json"
+	MediaTypeYAML        = "application/yaml"
+	MediaTypeCSV         = "text/csv"
+	MediaTypeMsgPack     = "application/msgpack"
+	MediaTypeNDJSON      = "application/x-ndjson"
+	MediaTypeEventStream = "text/event-stream"
+)
+
+// Encoder writes REST responses in a media type other than JSON
+type Encoder interface {
+	// Encode writes a response, v is the JSON value of the response, decoded with json.Number numbers
+	Encode(w io.Writer, v interface{}) error
+}
+
+// EncoderFunc is an Encoder function
+type EncoderFunc func(w io.Writer, v interface{}) error
+
+func (f EncoderFunc) Encode(w io.Writer, v interface{}) error {
+	return f(w, v)
+}
+
+// defaultEncoders are the encoders registered by NewMapping, JSON responses are written by writeJSON itself
+func defaultEncoders() map[string]Encoder {
+	return map[string]Encoder{
+		MediaTypeYAML:    EncoderFunc(encodeYAML),
+		MediaTypeCSV:     EncoderFunc(encodeCSV),
+		MediaTypeMsgPack: EncoderFunc(encodeMsgPack),
+	}
+}
+
+// RegisterEncoder registers the encoder of a media type, routes offer it if listed in their RouteOptions.Produces
+func (m *Mapping) RegisterEncoder(mediaType string, encoder Encoder) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.encoders == nil {
+		m.encoders = make(map[string]Encoder)
+	}
+	m.encoders[mediaType] = encoder
+}
+
+// WithEncoder registers the encoder of a media type when the mapping is created, so that the routes
+// producing it are prepared, eg. RegisterHandlers(binder, srv, "", handlerx.WithEncoder("application/xml", enc))
+func WithEncoder(mediaType string, encoder Encoder) MappingOption {
+	return func(m *Mapping) {
+		m.RegisterEncoder(mediaType, encoder)
+	}
+}
+
+// negotiateMediaType returns the offered media type preferred by the Accept header, or "" if none is acceptable.
+// Offers are tried in order, so the first one wins ties and requests without Accept.
+func negotiateMediaType(accept string, offers []string) string {
+	if strings.TrimSpace(accept) == "" {
+		return offers[0]
+	}
+
+	type acceptRange struct {
+		mediaType string
+		q         float64
+	}
+	ranges := make([]acceptRange, 0)
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+		ranges = append(ranges, acceptRange{mediaType: mediaType, q: q})
+	}
+
+	best, bestQ := "", 0.0
+	for _, offer := range offers {
+		// the most specific matching range gives the quality of an offer
+		q, specificity := 0.0, -1
+		for _, r := range ranges {
+			s := -1
+			switch {
+			case r.mediaType == offer:
+				s = 2
+			case strings.HasSuffix(r.mediaType, "/*") && strings.HasPrefix(offer, strings.TrimSuffix(r.mediaType, "*")):
+				s = 1
+			case r.mediaType == "*/*":
+				s = 0
+			}
+			if s > specificity {
+				q, specificity = r.q, s
+			}
+		}
+		if q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+	return best
+}
+
+// decodeJSONValue decodes a JSON response for the encoders
+func decodeJSONValue(b []byte) (interface{}, error) {
+	var v interface{}
+	if err := jsonDecode(bytes.NewReader(b), &v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// plainValue replaces the json.Number of a JSON value by int64 or float64
+func plainValue(v interface{}) interface{} {
+	switch vv := v.(type) {
+	case json.Number:
+		if i, err := vv.Int64(); err == nil {
+			return i
+		}
+		f, _ := vv.Float64()
+		return f
+	case []interface{}:
+		ret := make([]interface{}, len(vv))
+		for i, item := range vv {
+			ret[i] = plainValue(item)
+		}
+		return ret
+	case map[string]interface{}:
+		ret := make(map[string]interface{}, len(vv))
+		for k, item := range vv {
+			ret[k] = plainValue(item)
+		}
+		return ret
+	}
+	return v
+}
+
+func encodeYAML(w io.Writer, v interface{}) error {
+	b, err := yaml.Marshal(plainValue(v))
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
+
+// encodeCSV writes the `data` of a response as CSV, one row per element of a list of objects,
+// with the dotted paths of the nested fields as columns, eg. "owner.name" or "tags.0".
+// Responses without data, eg. errors, are written as one row.
+func encodeCSV(w io.Writer, v interface{}) error {
+	envelope, _ := v.(map[string]interface{})
+	var rows []interface{}
+	if data, ok := envelope["data"]; ok && data != nil {
+		if list, ok := data.([]interface{}); ok {
+			rows = list
+		} else {
+			rows = []interface{}{data}
+		}
+	} else {
+		row := make(map[string]interface{}, len(envelope))
+		for k, item := range envelope {
+			if k != "data" {
+				row[k] = item
+			}
+		}
+		rows = []interface{}{row}
+	}
+
+	records := make([]map[string]string, 0, len(rows))
+	columns := make(map[string]bool)
+	for _, row := range rows {
+		record := make(map[string]string)
+		flattenCSVValue(record, "", row)
+		for column := range record {
+			columns[column] = true
+		}
+		records = append(records, record)
+	}
+	header := make([]string, 0, len(columns))
+	for column := range columns {
+		header = append(header, column)
+	}
+	sort.Strings(header)
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	for _, record := range records {
+		line := make([]string, len(header))
+		for i, column := range header {
+			line[i] = record[column]
+		}
+		if err := cw.Write(line); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func flattenCSVValue(record map[string]string, prefix string, v interface{}) {
+	join := func(key string) string {
+		if prefix == "" {
+			return key
+		}
+		return prefix + "." + key
+	}
+
+	switch vv := v.(type) {
+	case map[string]interface{}:
+		for k, item := range vv {
+			flattenCSVValue(record, join(k), item)
+		}
+	case []interface{}:
+		for i, item := range vv {
+			flattenCSVValue(record, join(strconv.Itoa(i)), item)
+		}
+	case nil:
+		record[prefixOrValue(prefix)] = ""
+	default:
+		record[prefixOrValue(prefix)] = fmt.Sprintf("%v", vv)
+	}
+}
+
+// prefixOrValue names the column of a scalar row
+func prefixOrValue(prefix string) string {
+	if prefix == "" {
+		return "value"
+	}
+	return prefix
+}
@@ -0,0 +1,214 @@
+package handlerx
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNegotiateMediaType(t *testing.T) {
+	offers := []string{MediaTypeJSON, MediaTypeCSV, MediaTypeYAML}
+
+	tests := []struct {
+		Accept   string
+		Expected string
+	}{
+		{Accept: "", Expected: MediaTypeJSON},
+		{Accept: "*/*", Expected: MediaTypeJSON},
+		{Accept: "text/csv", Expected: MediaTypeCSV},
+		{Accept: "text/*", Expected: MediaTypeCSV},
+		{Accept: "text/html, application/yaml;q=0.9, */*;q=0.1", Expected: MediaTypeYAML},
+		{Accept: "application/json;q=0.5, text/csv", Expected: MediaTypeCSV},
+		{Accept: "*/*, application/json;q=0", Expected: MediaTypeCSV},
+		{Accept: "application/xml", Expected: ""},
+		{Accept: "text/csv;q=0", Expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Accept, func(t *testing.T) {
+			assert.Equal(t, tt.Expected, negotiateMediaType(tt.Accept, offers))
+		})
+	}
+}
+
+func TestEncoders(t *testing.T) {
+	v, err := decodeJSONValue([]byte(`{"code":0,"data":[` +
+		`{"id":"T1","done":false,"owner":{"name":"a, b"},"tags":["x","y"],"size":1.5},` +
+		`{"id":"T2","done":true,"owner":null,"tags":[]}],"total":2}`))
+	require.NoError(t, err)
+
+	t.Run("CSV", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, encodeCSV(&buf, v))
+		assert.Equal(t, "done,id,owner,owner.name,size,tags.0,tags.1\n"+
+			"false,T1,,\"a, b\",1.5,x,y\n"+
+			"true,T2,,,,,\n", buf.String())
+	})
+
+	t.Run("CSV of error", func(t *testing.T) {
+		v, err := decodeJSONValue([]byte(`{"code":404,"message":"todo not found","data":null}`))
+		require.NoError(t, err)
+
+		var buf bytes.Buffer
+		require.NoError(t, encodeCSV(&buf, v))
+		assert.Equal(t, "code,message\n404,todo not found\n", buf.String())
+	})
+
+	t.Run("YAML", func(t *testing.T) {
+		v, err := decodeJSONValue([]byte(`{"code":0,"data":{"id":"T1","size":1000000,"ratio":0.5}}`))
+		require.NoError(t, err)
+
+		var buf bytes.Buffer
+		require.NoError(t, encodeYAML(&buf, v))
+		assert.Equal(t, "code: 0\ndata:\n  id: T1\n  ratio: 0.5\n  size: 1000000\n", buf.String())
+	})
+
+	t.Run("MessagePack", func(t *testing.T) {
+		v, err := decodeJSONValue([]byte(`{"a":[1,-1,200,-200,70000,null,true,"x"],"b":1.5}`))
+		require.NoError(t, err)
+
+		var buf bytes.Buffer
+		require.NoError(t, encodeMsgPack(&buf, v))
+		assert.Equal(t, []byte{
+			0x82,
+			0xa1, 'a', 0x98, 0x01, 0xff, 0xd1, 0x00, 0xc8, 0xd1, 0xff, 0x38, 0xd2, 0x00, 0x01, 0x11, 0x70, 0xc0, 0xc3, 0xa1, 'x',
+			0xa1, 'b', 0xcb, 0x3f, 0xf8, 0, 0, 0, 0, 0, 0,
+		}, buf.Bytes())
+	})
+}
+
+func TestContentNegotiation(t *testing.T) {
+	m := newTestMapping()
+	m.SetRouteOptions(RouteOptionsMap{
+		"GET:/todos": {Paginate: true, Produces: []string{MediaTypeCSV, MediaTypeYAML, MediaTypeMsgPack}},
+	})
+	h := newTestMappingRouter(m, NewPreparedQueryCache(nil))
+
+	tests := []struct {
+		Name                string
+		Target              string
+		Accept              string
+		ExpectedStatus      int
+		ExpectedContentType string
+		Expected            string
+	}{
+		{
+			Name:                "CSV list",
+			Target:              "/todos",
+			Accept:              "text/csv",
+			ExpectedStatus:      http.StatusOK,
+			ExpectedContentType: MediaTypeCSV,
+			Expected:            "done,id,text\nfalse,T1,buy milk\ntrue,T2,walk dog\n",
+		},
+		{
+			Name:                "YAML list",
+			Target:              "/todos?fields=id",
+			Accept:              "application/yaml",
+			ExpectedStatus:      http.StatusOK,
+			ExpectedContentType: MediaTypeYAML,
+			Expected:            "code: 0\ndata:\n- id: T1\n- id: T2\npagination: {}\ntotal: 2\n",
+		},
+		{
+			Name:                "YAML error",
+			Target:              "/todos?fields=unknown",
+			Accept:              "application/yaml",
+			ExpectedStatus:      http.StatusBadRequest,
+			ExpectedContentType: MediaTypeYAML,
+			Expected:            "code: 400\ndata: null\nmessage: 'fields: unknown field \"unknown\"'\nrequest_id: test-request-id\n",
+		},
+		{
+			Name:                "JSON by default",
+			Target:              "/todos/T1",
+			ExpectedStatus:      http.StatusOK,
+			ExpectedContentType: MediaTypeJSON,
+			Expected:            `{"code":0,"data":{"done":false,"id":"T1","text":"buy milk"}}`,
+		},
+		{
+			Name:                "media type not produced by the route",
+			Target:              "/todos/T1",
+			Accept:              "text/csv",
+			ExpectedStatus:      http.StatusNotAcceptable,
+			ExpectedContentType: MediaTypeJSON,
+			Expected:            `{"code":406,"message":"not acceptable: text/csv","data":null,"request_id":"test-request-id"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest("GET", tt.Target, nil)
+			if tt.Accept != "" {
+				r.Header.Set("Accept", tt.Accept)
+			}
+			h.ServeHTTP(w, r)
+
+			assert.Equal(t, tt.ExpectedStatus, w.Code)
+			assert.Equal(t, tt.ExpectedContentType, w.Header().Get("Content-Type"))
+			assert.Equal(t, tt.Expected, w.Body.String())
+		})
+	}
+
+	t.Run("MessagePack list", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "/todos?fields=id", nil)
+		r.Header.Set("Accept", MediaTypeMsgPack)
+		h.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, MediaTypeMsgPack, w.Header().Get("Content-Type"))
+		assert.Equal(t, []byte{0x84, 0xa4, 'c', 'o', 'd', 'e', 0x00}, w.Body.Bytes()[:7])
+	})
+
+	t.Run("route without encoder", func(t *testing.T) {
+		m := newTestMapping()
+		m.SetRouteOptions(RouteOptionsMap{"GET:/todos": {Produces: []string{"application/xml"}}})
+
+		err := m.Prepare(testSchema)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `GET:/todos: no encoder for media type "application/xml"`)
+
+		m.RegisterEncoder("application/xml", EncoderFunc(encodeYAML))
+		assert.NoError(t, m.Prepare(testSchema))
+	})
+}
+
+// registerTestHandlers registers the test mapping as the RegisterHandlers generated by restgen does:
+// the mapping is created with the options, then prepared, and it panics if an operation is invalid
+func registerTestHandlers(binder RouteBinder, srv http.Handler, routes RouteOptionsMap, opts ...MappingOption) *Mapping {
+	mapping := newTestMapping(opts...)
+	mapping.SetRouteOptions(routes)
+	if err := mapping.Prepare(testSchema); err != nil {
+		panic(err)
+	}
+	mapping.Bind(binder, srv)
+	return mapping
+}
+
+func TestRegisterEncoderOption(t *testing.T) {
+	routes := RouteOptionsMap{"GET:/todos": {Produces: []string{MediaTypeJSON, "application/xml"}}}
+	srv := handler.New(newTestExecutableSchema())
+	srv.AddTransport(GET{})
+
+	assert.Panics(t, func() {
+		registerTestHandlers(ChiBinder{Router: chi.NewRouter()}, srv, routes)
+	})
+
+	r := chi.NewRouter()
+	assert.NotPanics(t, func() {
+		registerTestHandlers(ChiBinder{Router: r}, srv, routes, WithEncoder("application/xml", EncoderFunc(encodeYAML)))
+	})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/todos?fields=id", nil)
+	req.Header.Set("Accept", "application/xml")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/xml", w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Body.String(), "code: 0\n")
+}
@@ -0,0 +1,85 @@
+package handlerx
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/99designs/gqlgen/graphql/errcode"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// ErrorMapper maps the GraphQL errors of REST requests to HTTP statuses
+type ErrorMapper interface {
+	// HTTPStatus returns the HTTP status of an error, or 0 to use the default status
+	HTTPStatus(err *gqlerror.Error) int
+}
+
+// ErrorMapperFunc is an ErrorMapper function
+type ErrorMapperFunc func(err *gqlerror.Error) int
+
+func (f ErrorMapperFunc) HTTPStatus(err *gqlerror.Error) int {
+	return f(err)
+}
+
+// ErrorCodeMapper maps the `code` extension of errors to HTTP statuses, eg.
+// ErrorCodeMapper{"CONFLICT": http.StatusConflict, errcode.ValidationFailed: http.StatusBadRequest}.
+// Other codes get the default status.
+type ErrorCodeMapper map[string]int
+
+func (m ErrorCodeMapper) HTTPStatus(err *gqlerror.Error) int {
+	return m[errorCode(err)]
+}
+
+// DefaultErrorMapper maps numeric codes to themselves, errors of gqlgen's parse and
+// validation phases, and errors without code, to 422, and any other code to 500
+var DefaultErrorMapper ErrorMapper = ErrorMapperFunc(defaultHTTPStatus)
+
+func defaultHTTPStatus(err *gqlerror.Error) int {
+	code := errorCode(err)
+	if code == "" {
+		return http.StatusUnprocessableEntity
+	}
+	if numRegexp.MatchString(code) {
+		status, _ := strconv.Atoi(code)
+		return status
+	}
+	if code == errcode.ValidationFailed || code == errcode.ParseFailed {
+		return http.StatusUnprocessableEntity
+	}
+	return http.StatusInternalServerError
+}
+
+// errorCode returns the `code` extension of an error, or "" if not set
+func errorCode(err *gqlerror.Error) string {
+	code, ok := err.Extensions["code"]
+	if !ok || code == nil {
+		return ""
+	}
+	if str, ok := code.(string); ok {
+		return str
+	}
+	return fmt.Sprintf("%v", code)
+}
+
+// SetErrorMapper sets how errors are mapped to HTTP statuses, the DefaultErrorMapper is used if nil
+func (m *Mapping) SetErrorMapper(mapper ErrorMapper) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.errorMapper = mapper
+}
+
+// errorMapperFor returns the error mapper of the mapping carried by the context
+func errorMapperFor(ctx context.Context) ErrorMapper {
+	if m := GetMapping(ctx); m != nil {
+		m.mu.RLock()
+		defer m.mu.RUnlock()
+
+		if m.errorMapper != nil {
+			return m.errorMapper
+		}
+	}
+	return DefaultErrorMapper
+}
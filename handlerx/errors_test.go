@@ -0,0 +1,115 @@
+package handlerx
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/99designs/gqlgen/graphql/errcode"
+	"github.com/stretchr/testify/assert"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+func TestDefaultErrorMapper(t *testing.T) {
+	tests := []struct {
+		Name       string
+		Extensions map[string]interface{}
+		Expected   int
+	}{
+		{Name: "no code", Expected: http.StatusUnprocessableEntity},
+		{Name: "numeric code", Extensions: map[string]interface{}{"code": "404"}, Expected: http.StatusNotFound},
+		{Name: "numeric value", Extensions: map[string]interface{}{"code": 409}, Expected: http.StatusConflict},
+		{Name: "validation failed", Extensions: map[string]interface{}{"code": errcode.ValidationFailed}, Expected: http.StatusUnprocessableEntity},
+		{Name: "parse failed", Extensions: map[string]interface{}{"code": errcode.ParseFailed}, Expected: http.StatusUnprocessableEntity},
+		{Name: "other code", Extensions: map[string]interface{}{"code": "NOT_FOUND"}, Expected: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			err := &gqlerror.Error{Message: "oops", Extensions: tt.Extensions}
+			assert.Equal(t, tt.Expected, DefaultErrorMapper.HTTPStatus(err))
+		})
+	}
+}
+
+func TestRouteStatus(t *testing.T) {
+	m := newTestMapping()
+	m.SetRouteOptions(RouteOptionsMap{
+		"POST:/todos":        {Status: http.StatusCreated},
+		"DELETE:/todos/{id}": {Status: http.StatusNoContent},
+	})
+	h := newTestMappingRouter(m, NewPreparedQueryCache(nil))
+
+	tests := []struct {
+		Name           string
+		Method         string
+		Target         string
+		Body           string
+		ExpectedStatus int
+		Expected       string
+	}{
+		{
+			Name:           "created",
+			Method:         "POST",
+			Target:         "/todos",
+			Body:           `{"input":{"text":"buy milk","userId":"U1"}}`,
+			ExpectedStatus: http.StatusCreated,
+			Expected:       `{"code":0,"data":{"done":false,"id":"T3","text":"buy milk"}}`,
+		},
+		{
+			Name:           "no content",
+			Method:         "DELETE",
+			Target:         "/todos/T1",
+			ExpectedStatus: http.StatusNoContent,
+		},
+		{
+			Name:           "default status",
+			Method:         "GET",
+			Target:         "/todos/T1",
+			ExpectedStatus: http.StatusOK,
+			Expected:       `{"code":0,"data":{"done":false,"id":"T1","text":"buy milk"}}`,
+		},
+		{
+			Name:           "error of a route with status",
+			Method:         "POST",
+			Target:         "/todos",
+			Body:           `{"input":{"text":"buy milk"}}`,
+			ExpectedStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			Name:           "unmapped error code",
+			Method:         "GET",
+			Target:         "/todos/missing",
+			ExpectedStatus: http.StatusInternalServerError,
+			Expected:       `{"code":500,"message":"todo not found","data":null,"request_id":"test-request-id"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(tt.Method, tt.Target, strings.NewReader(tt.Body))
+			r.Header.Set("Content-Type", "application/json")
+			h.ServeHTTP(w, r)
+
+			assert.Equal(t, tt.ExpectedStatus, w.Code)
+			if tt.Expected != "" {
+				assert.JSONEq(t, tt.Expected, w.Body.String())
+			} else if tt.ExpectedStatus == http.StatusNoContent {
+				assert.Empty(t, w.Body.String())
+			}
+		})
+	}
+
+	t.Run("error mapper", func(t *testing.T) {
+		m.SetErrorMapper(ErrorCodeMapper{"NOT_FOUND": http.StatusNotFound})
+		defer m.SetErrorMapper(nil)
+
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", "/todos/missing", nil))
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.JSONEq(t, `{"code":404,"message":"todo not found","data":null,"request_id":"test-request-id"}`, w.Body.String())
+	})
+}
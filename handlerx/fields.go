@@ -0,0 +1,213 @@
+package handlerx
+
+import (
+	"fmt"
+	"strings"
+)
+
+// fieldsParam is the query parameter used by REST callers to narrow the response fields,
+// eg. "?fields=id,name,user{id}" or "?fields=id,user.id"
+const fieldsParam = "fields"
+
+// selectionNode is a parsed field selection, eg. "{id,user{id,name},metrics(window:\"5m\"){avg}}"
+type selectionNode struct {
+	names     []string
+	arguments map[string]string
+	children  map[string]*selectionNode
+}
+
+func newSelectionNode() *selectionNode {
+	return &selectionNode{arguments: make(map[string]string), children: make(map[string]*selectionNode)}
+}
+
+func (n *selectionNode) child(name string) *selectionNode {
+	if c, ok := n.children[name]; ok {
+		return c
+	}
+	c := newSelectionNode()
+	n.names = append(n.names, name)
+	n.children[name] = c
+	return c
+}
+
+func (n *selectionNode) String() string {
+	if len(n.names) == 0 {
+		return ""
+	}
+	fields := make([]string, 0, len(n.names))
+	for _, name := range n.names {
+		fields = append(fields, name+n.arguments[name]+n.children[name].String())
+	}
+	return "{" + strings.Join(fields, ",") + "}"
+}
+
+// parseSelection parses a comma separated field list, where nested fields are
+// given either in braces "user{id}" or with dotted paths "user.id".
+func parseSelection(s string) (*selectionNode, error) {
+	root := newSelectionNode()
+	rest, err := parseSelectionList(root, s, false)
+	if err != nil {
+		return nil, err
+	}
+	if rest != "" {
+		return nil, fmt.Errorf("unexpected %q", rest)
+	}
+	return root, nil
+}
+
+func parseSelectionList(parent *selectionNode, s string, nested bool) (string, error) {
+	for {
+		s = strings.TrimSpace(s)
+		end := scanSelectionToken(s)
+		path := strings.TrimSpace(s[:end])
+		s = s[end:]
+
+		// arguments of the last field, eg. `metrics(window:"5m")`
+		arguments := ""
+		if i := strings.Index(path, "("); i >= 0 && !strings.HasPrefix(path, "...") {
+			path, arguments = strings.TrimSpace(path[:i]), path[i:]
+		}
+
+		node := parent
+		if strings.HasPrefix(path, "...") {
+			// inline fragment of an interface or union, eg. "... on Vm{id}"
+			node = node.child(strings.Join(strings.Fields(path), " "))
+		} else if path != "" {
+			for _, name := range strings.Split(path, ".") {
+				name = strings.TrimSpace(name)
+				if name == "" {
+					return "", fmt.Errorf("empty field name in %q", path)
+				}
+				node = node.child(name)
+			}
+		}
+		if arguments != "" {
+			if path == "" {
+				return "", fmt.Errorf("missing field name before %q", arguments)
+			}
+			names := strings.Split(path, ".")
+			parent.arguments[names[len(names)-1]] = arguments
+			if len(names) > 1 {
+				return "", fmt.Errorf("arguments are only allowed on a single field name, got %q", path)
+			}
+		}
+
+		if strings.HasPrefix(s, "{") {
+			if path == "" {
+				return "", fmt.Errorf("missing field name before '{'")
+			}
+			rest, err := parseSelectionList(node, s[1:], true)
+			if err != nil {
+				return "", err
+			}
+			if !strings.HasPrefix(rest, "}") {
+				return "", fmt.Errorf("missing '}' after %q", path)
+			}
+			s = strings.TrimSpace(rest[1:])
+		}
+
+		switch {
+		case strings.HasPrefix(s, ","):
+			s = s[1:]
+		case strings.HasPrefix(s, "}"):
+			if !nested {
+				return "", fmt.Errorf("unexpected '}'")
+			}
+			return s, nil
+		case s == "":
+			if nested {
+				return "", fmt.Errorf("missing '}'")
+			}
+			return "", nil
+		default:
+			return "", fmt.Errorf("unexpected %q", s)
+		}
+	}
+}
+
+// scanSelectionToken returns the end of the field path at the start of s,
+// skipping over argument lists and the string values in them.
+func scanSelectionToken(s string) int {
+	depth, quoted := 0, false
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case quoted && c == '\\':
+			i++
+		case c == '"':
+			quoted = !quoted
+		case quoted:
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case depth == 0 && (c == ',' || c == '{' || c == '}'):
+			return i
+		}
+	}
+	return len(s)
+}
+
+// narrowSelection checks the requested fields against the default selection of an
+// operation, and returns the selection restricted to them. A requested field without
+// sub fields keeps its whole default selection.
+func narrowSelection(defaultSelection string, fields string) (string, error) {
+	def, err := parseSelection(strings.TrimSuffix(strings.TrimPrefix(defaultSelection, "{"), "}"))
+	if err != nil {
+		return "", err
+	}
+	req, err := parseSelection(fields)
+	if err != nil {
+		return "", fmt.Errorf("invalid fields %q: %s", fields, err.Error())
+	}
+
+	narrowed, err := intersectSelection(def, req, "")
+	if err != nil {
+		return "", err
+	}
+	return narrowed.String(), nil
+}
+
+func intersectSelection(def *selectionNode, req *selectionNode, path string) (*selectionNode, error) {
+	if len(req.names) == 0 {
+		return def, nil
+	}
+
+	ret := newSelectionNode()
+	for _, name := range req.names {
+		if req.arguments[name] != "" {
+			return nil, fmt.Errorf("arguments are not allowed on field %q", path+name)
+		}
+		parent, parentDef, defChild := ret, def, def.children[name]
+		if defChild == nil {
+			// fields of an interface or union may only be selected in one of its inline fragments
+			for _, fragment := range def.names {
+				if strings.HasPrefix(fragment, "... on ") && def.children[fragment].children[name] != nil {
+					parent, parentDef, defChild = ret.child(fragment), def.children[fragment], def.children[fragment].children[name]
+					break
+				}
+			}
+		}
+		if defChild == nil {
+			return nil, fmt.Errorf("unknown field %q", path+name)
+		}
+		if len(defChild.names) == 0 && len(req.children[name].names) > 0 {
+			return nil, fmt.Errorf("field %q has no sub fields", path+name)
+		}
+		child, err := intersectSelection(defChild, req.children[name], path+name+".")
+		if err != nil {
+			return nil, err
+		}
+		parent.names = append(parent.names, name)
+		parent.arguments[name] = parentDef.arguments[name]
+		parent.children[name] = child
+	}
+
+	// the type of an interface or union is always returned
+	if _, ok := def.children["__typename"]; ok {
+		if _, ok := ret.children["__typename"]; !ok {
+			ret.names = append([]string{"__typename"}, ret.names...)
+			ret.children["__typename"] = newSelectionNode()
+		}
+	}
+	return ret, nil
+}
@@ -0,0 +1,83 @@
+package handlerx
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNarrowSelection(t *testing.T) {
+	tests := []struct {
+		Name        string
+		Fields      string
+		Expected    string
+		ShouldError bool
+	}{
+		{Name: "top level fields", Fields: "id,name", Expected: "{id,name}"},
+		{Name: "nested braces", Fields: "id,user{id}", Expected: "{id,user{id}}"},
+		{Name: "dotted path", Fields: "id,user.id,user.group.name", Expected: "{id,user{id,group{name}}}"},
+		{Name: "whole nested object", Fields: "user", Expected: "{user{id,name,group{id,name}}}"},
+		{Name: "spaces", Fields: " id , user { name } ", Expected: "{id,user{name}}"},
+		{Name: "unknown field", Fields: "id,password", ShouldError: true},
+		{Name: "unknown nested field", Fields: "user.password", ShouldError: true},
+		{Name: "sub fields of scalar", Fields: "id{x}", ShouldError: true},
+		{Name: "unbalanced braces", Fields: "user{id", ShouldError: true},
+		{Name: "extra brace", Fields: "id}", ShouldError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			selection, err := narrowSelection("{id,name,user{id,name,group{id,name}}}", tt.Fields)
+			if tt.ShouldError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.Expected, selection)
+			}
+		})
+	}
+}
+
+func TestNarrowAbstractSelection(t *testing.T) {
+	tests := []struct {
+		Name        string
+		Fields      string
+		Expected    string
+		ShouldError bool
+	}{
+		{Name: "common fields", Fields: "id", Expected: "{__typename,id}"},
+		{Name: "fragment fields", Fields: "id,vcpu,size", Expected: "{__typename,id,... on Vm{vcpu},... on Volume{size}}"},
+		{Name: "explicit fragment", Fields: "... on Vm{host.id}", Expected: "{__typename,... on Vm{host{id}}}"},
+		{Name: "unknown field", Fields: "id,secret", ShouldError: true},
+		{Name: "field arguments", Fields: "id,metrics", Expected: `{__typename,id,... on Vm{metrics(window:"5m",label:"a,b{c}"){avg}}}`},
+		{Name: "arguments not allowed", Fields: `metrics(window:"1h")`, ShouldError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			selection, err := narrowSelection(`{__typename,id,name,... on Vm{vcpu,host{id,name},metrics(window:"5m",label:"a,b{c}"){avg}},... on Volume{size}}`, tt.Fields)
+			if tt.ShouldError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.Expected, selection)
+			}
+		})
+	}
+}
+
+func TestFieldsParameter(t *testing.T) {
+	h := newTestRouter(NewPreparedQueryCache(nil))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/todos/T1?fields=id,done", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"code":0,"data":{"id":"T1","done":false}}`, w.Body.String())
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/todos/T1?fields=id,secret", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.JSONEq(t, `{"code":400,"message":"fields: unknown field \"secret\"","data":null,"request_id":"test-request-id"}`, w.Body.String())
+}
@@ -10,7 +10,12 @@ import (
 
 // DELETE implements the DELETE side of the default HTTP transport
 // defined in https://github.com/APIs-guru/graphql-over-http#post
-type DELETE struct{}
+type DELETE struct {
+	// Mapping converts REST requests, the one carried by the request context is used if nil
+	Mapping *Mapping
+	// Tracer traces the requests if set, see WithTracer
+	Tracer *Tracer
+}
 
 var _ graphql.Transport = DELETE{}
 
@@ -24,7 +29,10 @@ func (h DELETE) Do(w http.ResponseWriter, r *http.Request, exec graphql.GraphExe
 	// https://stackoverflow.com/questions/43021058/golang-read-request-body-multiple-times
 	body, _ := ioutil.ReadAll(r.Body)
 	r.Body = ioutil.NopCloser(bytes.NewBuffer(body))
-	ctx := createResponseContext(r.Context())
+	mapping := requestMapping(h.Mapping, r)
+	ctx := createResponseContext(WithMapping(r.Context(), mapping))
+	entry, ctx, w := logRequest(ctx, w, r, h.Tracer)
+	defer entry.done()
 
 	params := &graphql.RawParams{}
 	params.ReadTime.Start = graphql.Now()
@@ -33,11 +41,13 @@ func (h DELETE) Do(w http.ResponseWriter, r *http.Request, exec graphql.GraphExe
 	isRESTful := false
 	if params.Query == "" { // For RESTful request, convert to GraphQL query
 		isRESTful = true
+		entry.isRESTful = true
 
-		queryString, err := convertHTTPRequestToGraphQLQuery(r, params, body)
+		entry.translate.Start = graphql.Now()
+		queryString, err := mapping.convertHTTPRequestToGraphQLQuery(ctx, r, params, body)
+		entry.translate.End = graphql.Now()
 		if err != nil {
-			w.WriteHeader(http.StatusBadRequest)
-			writeJSONErrorf(ctx, w, http.StatusUnprocessableEntity, isRESTful, "query body could not be parsed: "+err.Error())
+			writeMappingError(ctx, w, isRESTful, "query body could not be parsed: ", err)
 			return
 		}
 		params.Query = queryString
@@ -46,17 +56,24 @@ func (h DELETE) Do(w http.ResponseWriter, r *http.Request, exec graphql.GraphExe
 
 	params.ReadTime.End = graphql.Now()
 
-	dbgPrintf("HTTP %s %s: %s %s", r.Method, r.URL.Path, params.Query, params.Variables)
+	entry.debug(params)
 
 	rc, err := exec.CreateOperationContext(ctx, params)
+	entry.setOperation(rc)
 	if err != nil {
-		w.WriteHeader(statusFor(err))
+		if !isRESTful {
+			// RESTful responses get the status of their errors from writeJSON
+			w.WriteHeader(statusFor(err))
+		}
 		resp := exec.DispatchError(graphql.WithOperationContext(ctx, rc), err)
 		writeJSON(ctx, w, resp, isRESTful)
 		return
 	}
 
 	ctx = graphql.WithOperationContext(ctx, rc)
+	ctx, span := entry.execute(ctx)
 	responses, ctx := exec.DispatchOperation(ctx, rc)
-	writeJSON(ctx, w, responses(ctx), isRESTful)
+	response := responses(ctx)
+	endSpan(span, response.Errors)
+	writeJSON(ctx, w, response, isRESTful)
 }
@@ -12,7 +12,12 @@ import (
 
 // GET implements the GET side of the default HTTP transport
 // defined in https://github.com/APIs-guru/graphql-over-http#get
-type GET struct{}
+type GET struct {
+	// Mapping converts REST requests, the one carried by the request context is used if nil
+	Mapping *Mapping
+	// Tracer traces the requests if set, see WithTracer
+	Tracer *Tracer
+}
 
 var _ graphql.Transport = GET{}
 
@@ -21,16 +26,31 @@ func (h GET) Supports(r *http.Request) bool {
 		return false
 	}
 
-	return r.Method == "GET"
+	return r.Method == "GET" || r.Method == "HEAD"
+}
+
+// headResponseWriter drops the body of the response to a HEAD request
+type headResponseWriter struct {
+	http.ResponseWriter
+}
+
+func (w headResponseWriter) Write(b []byte) (int, error) {
+	return len(b), nil
 }
 
 func (h GET) Do(w http.ResponseWriter, r *http.Request, exec graphql.GraphExecutor) {
+	if r.Method == http.MethodHead {
+		w = headResponseWriter{w}
+	}
 	w.Header().Set("Content-Type", "application/json")
 
 	// https://stackoverflow.com/questions/43021058/golang-read-request-body-multiple-times
 	body, _ := ioutil.ReadAll(r.Body)
 	r.Body = ioutil.NopCloser(bytes.NewBuffer(body))
-	ctx := createResponseContext(r.Context())
+	mapping := requestMapping(h.Mapping, r)
+	ctx := createResponseContext(WithMapping(r.Context(), mapping))
+	entry, ctx, w := logRequest(ctx, w, r, h.Tracer)
+	defer entry.done()
 
 	params := &graphql.RawParams{
 		Query:         r.URL.Query().Get("query"),
@@ -58,11 +78,13 @@ func (h GET) Do(w http.ResponseWriter, r *http.Request, exec graphql.GraphExecut
 	isRESTful := false
 	if params.Query == "" { // For RESTful request, convert to GraphQL query
 		isRESTful = true
+		entry.isRESTful = true
 
-		queryString, err := convertHTTPRequestToGraphQLQuery(r, params, body)
+		entry.translate.Start = graphql.Now()
+		queryString, err := mapping.convertHTTPRequestToGraphQLQuery(ctx, r, params, body)
+		entry.translate.End = graphql.Now()
 		if err != nil {
-			w.WriteHeader(http.StatusBadRequest)
-			writeJSONErrorf(ctx, w, http.StatusUnprocessableEntity, isRESTful, "json body could not be decoded: "+err.Error())
+			writeMappingError(ctx, w, isRESTful, "json body could not be decoded: ", err)
 			return
 		}
 		params.Query = queryString
@@ -71,11 +93,15 @@ func (h GET) Do(w http.ResponseWriter, r *http.Request, exec graphql.GraphExecut
 
 	params.ReadTime.End = graphql.Now()
 
-	dbgPrintf("HTTP %s %s: %s %s", r.Method, r.URL.Path, params.Query, params.Variables)
+	entry.debug(params)
 
 	rc, err := exec.CreateOperationContext(ctx, params)
+	entry.setOperation(rc)
 	if err != nil {
-		w.WriteHeader(statusFor(err))
+		if !isRESTful {
+			// RESTful responses get the status of their errors from writeJSON
+			w.WriteHeader(statusFor(err))
+		}
 		resp := exec.DispatchError(graphql.WithOperationContext(ctx, rc), err)
 		writeJSON(ctx, w, resp, isRESTful)
 		return
@@ -83,11 +109,14 @@ func (h GET) Do(w http.ResponseWriter, r *http.Request, exec graphql.GraphExecut
 
 	op := rc.Doc.Operations.ForName(rc.OperationName)
 	if op.Operation != ast.Query {
-		w.WriteHeader(http.StatusNotAcceptable)
+		writeErrorHeader(ctx, w, isRESTful, http.StatusNotAcceptable)
 		writeJSONError(ctx, w, http.StatusBadRequest, isRESTful, "GET requests only allow query operations")
 		return
 	}
 
+	ctx, span := entry.execute(ctx)
 	responses, ctx := exec.DispatchOperation(ctx, rc)
-	writeJSON(ctx, w, responses(ctx), isRESTful)
+	response := responses(ctx)
+	endSpan(span, response.Errors)
+	writeJSON(ctx, w, response, isRESTful)
 }
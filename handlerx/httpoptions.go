@@ -2,25 +2,81 @@ package handlerx
 
 import (
 	"net/http"
+	"sort"
+	"strings"
 
 	"github.com/99designs/gqlgen/graphql"
 )
 
-// Options responds to http OPTIONS and HEAD requests
+// Options responds to http OPTIONS requests of the GraphQL endpoint, the OPTIONS
+// requests of REST routes are answered by Mapping.Bind from the route table
 type Options struct{}
 
 var _ graphql.Transport = Options{}
 
 func (o Options) Supports(r *http.Request) bool {
-	return r.Method == "HEAD" || r.Method == "OPTIONS"
+	return r.Method == "OPTIONS"
 }
 
 func (o Options) Do(w http.ResponseWriter, r *http.Request, exec graphql.GraphExecutor) {
-	switch r.Method {
-	case http.MethodOptions:
-		w.WriteHeader(http.StatusOK)
-		w.Header().Set("Allow", "OPTIONS, GET, POST, PUT, DELETE")
-	case http.MethodHead:
-		w.WriteHeader(http.StatusMethodNotAllowed)
+	w.Header().Set("Allow", "OPTIONS, GET, HEAD, POST, PUT, DELETE")
+	w.WriteHeader(http.StatusOK)
+}
+
+// allowedMethodOrder is the order of the methods listed in the Allow header
+var allowedMethodOrder = []string{
+	http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut,
+	http.MethodPatch, http.MethodDelete, http.MethodOptions,
+}
+
+// allowedMethods returns the methods of the REST routes registered for a route pattern,
+// including HEAD for GET routes but subscriptions, and OPTIONS
+func (m *Mapping) allowedMethods(pattern string) []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	methods := map[string]bool{http.MethodOptions: true}
+	if pattern == m.batchPattern {
+		methods[http.MethodPost] = true
 	}
+	for route := range m.operations {
+		kv := strings.SplitN(route, ":", 2)
+		if kv[1] != pattern {
+			continue
+		}
+		methods[kv[0]] = true
+		if kv[0] == http.MethodGet && !m.getRouteOptions(route).Subscription {
+			methods[http.MethodHead] = true
+		}
+	}
+
+	allowed := make([]string, 0, len(methods))
+	for _, method := range allowedMethodOrder {
+		if methods[method] {
+			allowed = append(allowed, method)
+			delete(methods, method)
+		}
+	}
+	others := make([]string, 0, len(methods))
+	for method := range methods {
+		others = append(others, method)
+	}
+	sort.Strings(others)
+	return append(allowed, others...)
+}
+
+// optionsHandler answers the OPTIONS requests and the CORS preflight requests of REST routes
+func (m *Mapping) optionsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pattern, _, _ := requestRoute(r)
+		methods := m.allowedMethods(pattern)
+
+		if m.writePreflightHeaders(w, r, methods) {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		w.Header().Set("Allow", strings.Join(methods, ", "))
+		w.WriteHeader(http.StatusOK)
+	})
 }
@@ -11,7 +11,12 @@ import (
 
 // POST implements the POST side of the default HTTP transport
 // defined in https://github.com/APIs-guru/graphql-over-http#post
-type POST struct{}
+type POST struct {
+	// Mapping converts REST requests, the one carried by the request context is used if nil
+	Mapping *Mapping
+	// Tracer traces the requests if set, see WithTracer
+	Tracer *Tracer
+}
 
 var _ graphql.Transport = POST{}
 
@@ -39,7 +44,10 @@ func (h POST) Do(w http.ResponseWriter, r *http.Request, exec graphql.GraphExecu
 	// https://stackoverflow.com/questions/43021058/golang-read-request-body-multiple-times
 	body, _ := ioutil.ReadAll(r.Body)
 	r.Body = ioutil.NopCloser(bytes.NewBuffer(body))
-	ctx := createResponseContext(r.Context())
+	mapping := requestMapping(h.Mapping, r)
+	ctx := createResponseContext(WithMapping(r.Context(), mapping))
+	entry, ctx, w := logRequest(ctx, w, r, h.Tracer)
+	defer entry.done()
 
 	var params *graphql.RawParams
 	start := graphql.Now()
@@ -58,11 +66,13 @@ func (h POST) Do(w http.ResponseWriter, r *http.Request, exec graphql.GraphExecu
 	isRESTful := false
 	if params.Query == "" { // For RESTful request, convert to GraphQL query
 		isRESTful = true
+		entry.isRESTful = true
 
-		queryString, err := convertHTTPRequestToGraphQLQuery(r, params, body)
+		entry.translate.Start = graphql.Now()
+		queryString, err := mapping.convertHTTPRequestToGraphQLQuery(ctx, r, params, body)
+		entry.translate.End = graphql.Now()
 		if err != nil {
-			w.WriteHeader(http.StatusBadRequest)
-			writeJSONErrorf(ctx, w, http.StatusUnprocessableEntity, isRESTful, "query body could not be parsed: "+err.Error())
+			writeMappingError(ctx, w, isRESTful, "query body could not be parsed: ", err)
 			return
 		}
 		params.Query = queryString
@@ -75,18 +85,25 @@ func (h POST) Do(w http.ResponseWriter, r *http.Request, exec graphql.GraphExecu
 	}
 
 	rc, err := exec.CreateOperationContext(ctx, params)
+	entry.setOperation(rc)
 	if err != nil {
-		w.WriteHeader(statusFor(err))
+		if !isRESTful {
+			// RESTful responses get the status of their errors from writeJSON
+			w.WriteHeader(statusFor(err))
+		}
 		resp := exec.DispatchError(graphql.WithOperationContext(ctx, rc), err)
 		writeJSON(ctx, w, resp, isRESTful)
 		return
 	}
 
 	if rc.Operation.Name != "IntrospectionQuery" {
-		dbgPrintf("HTTP %s %s: %s %s", r.Method, r.URL.Path, params.Query, params.Variables)
+		entry.debug(params)
 	}
 
 	ctx = graphql.WithOperationContext(ctx, rc)
+	ctx, span := entry.execute(ctx)
 	responses, ctx := exec.DispatchOperation(ctx, rc)
-	writeJSON(ctx, w, responses(ctx), isRESTful)
+	response := responses(ctx)
+	endSpan(span, response.Errors)
+	writeJSON(ctx, w, response, isRESTful)
 }
@@ -0,0 +1,176 @@
+package handlerx
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+)
+
+// SSE implements the server-sent events transport of the REST subscription routes, see
+// https://html.spec.whatwg.org/multipage/server-sent-events.html. Each payload of the subscription
+// is sent as one event, with the data of the REST response envelope, eg.
+//
+//	id: 1
+//	data: {"code":0,"data":{"id":"T1","text":"buy milk"}}
+//
+// Events are numbered from 1 on each stream, or from the Last-Event-ID sent by a reconnecting
+// client, which resolvers read with ResponseContext.LastEventID to resume after the events received.
+// It must be added to the server before GET, which would otherwise take the subscription routes.
+type SSE struct {
+	// Mapping converts REST requests, the one carried by the request context is used if nil
+	Mapping *Mapping
+	// KeepAlivePingInterval is the interval of the comments sent to keep idle streams open, 0 disables them
+	KeepAlivePingInterval time.Duration
+	// Tracer traces the streams if set, their execute span ends when they are closed, see WithTracer
+	Tracer *Tracer
+}
+
+var _ graphql.Transport = SSE{}
+
+func (h SSE) Supports(r *http.Request) bool {
+	if r.Header.Get("Upgrade") != "" || r.Method != http.MethodGet {
+		return false
+	}
+
+	mapping := requestMapping(h.Mapping, r)
+	if mapping == nil {
+		return false
+	}
+	pattern, _, _ := requestRoute(r)
+	return mapping.streamMediaType(http.MethodGet+":"+pattern) == MediaTypeEventStream
+}
+
+func (h SSE) Do(w http.ResponseWriter, r *http.Request, exec graphql.GraphExecutor) {
+	serveSubscription(w, r, exec, requestMapping(h.Mapping, r), h.Tracer, MediaTypeEventStream,
+		func(ctx context.Context, w http.ResponseWriter, flusher http.Flusher, responses graphql.ResponseHandler) {
+			h.stream(ctx, w, r, flusher, responses)
+		})
+}
+
+// serveSubscription runs the subscription of a REST route, and streams its payloads as mediaType with stream
+// once the response headers are sent. The stream is logged and traced as one request, until it is closed.
+func serveSubscription(w http.ResponseWriter, r *http.Request, exec graphql.GraphExecutor, mapping *Mapping, tracer *Tracer,
+	mediaType string, stream func(ctx context.Context, w http.ResponseWriter, flusher http.Flusher, responses graphql.ResponseHandler)) {
+	w.Header().Set("Content-Type", "application/json")
+
+	ctx, cancel := context.WithCancel(createResponseContext(WithMapping(r.Context(), mapping)))
+	defer cancel()
+	responseCtx := GetResponseContext(ctx)
+	responseCtx.lastEventID = r.Header.Get("Last-Event-ID")
+	entry, ctx, w := logRequest(ctx, w, r, tracer)
+	defer entry.done()
+	entry.isRESTful = true
+
+	params := &graphql.RawParams{}
+	params.ReadTime.Start = graphql.Now()
+	entry.translate.Start = graphql.Now()
+	queryString, err := mapping.convertHTTPRequestToGraphQLQuery(ctx, r, params, nil)
+	entry.translate.End = graphql.Now()
+	if err != nil {
+		writeMappingError(ctx, w, true, "", err)
+		return
+	}
+	params.Query = queryString
+	params.ReadTime.End = graphql.Now()
+
+	entry.debug(params)
+
+	rc, errs := exec.CreateOperationContext(ctx, params)
+	entry.setOperation(rc)
+	if errs != nil {
+		resp := exec.DispatchError(graphql.WithOperationContext(ctx, rc), errs)
+		writeJSON(ctx, w, resp, true)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeErrorHeader(ctx, w, true, http.StatusInternalServerError)
+		writeJSONError(ctx, w, http.StatusInternalServerError, true, "streaming unsupported")
+		return
+	}
+
+	ctx, span := entry.execute(ctx)
+	defer span.End()
+	responses, ctx := exec.DispatchOperation(ctx, rc)
+
+	w.Header().Set("Content-Type", mediaType)
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	stream(ctx, w, flusher, responses)
+}
+
+// stream writes the payloads of a subscription as server-sent events
+func (h SSE) stream(ctx context.Context, w http.ResponseWriter, r *http.Request, flusher http.Flusher, responses graphql.ResponseHandler) {
+	// the payloads are read in the background, so that keepalive comments can be sent while waiting
+	events := make(chan *graphql.Response)
+	go func() {
+		defer close(events)
+		for {
+			response := responses(ctx)
+			if response == nil {
+				return
+			}
+			select {
+			case events <- response:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var keepAlive <-chan time.Time
+	if h.KeepAlivePingInterval > 0 {
+		ticker := time.NewTicker(h.KeepAlivePingInterval)
+		defer ticker.Stop()
+		keepAlive = ticker.C
+	}
+
+	id, _ := strconv.ParseInt(GetResponseContext(ctx).lastEventID, 10, 64)
+	for {
+		select {
+		case response, ok := <-events:
+			if !ok {
+				return
+			}
+			id++
+			if err := writeEvent(ctx, w, id, response); err != nil {
+				dbgPrintf("HTTP %s %s: event %d could not be written: %v", r.Method, r.URL.Path, id, err)
+				return
+			}
+		case <-keepAlive:
+			if _, err := w.Write([]byte(": keepalive\n\n")); err != nil {
+				return
+			}
+		case <-ctx.Done():
+			return
+		}
+		flusher.Flush()
+	}
+}
+
+// writeEvent writes one payload of a subscription as a server-sent event
+func writeEvent(ctx context.Context, w http.ResponseWriter, id int64, r *graphql.Response) error {
+	data, err := unwrapData(r.Data)
+	if err != nil {
+		return err
+	}
+	response := &RESTResponse{Data: data}
+	if len(r.Errors) > 0 {
+		response.Code, response.CodeStr, response.Message = parseErrCodeFromGqlErrors(r.Errors, errorMapperFor(ctx))
+	}
+
+	b, err := json.Marshal(response)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\ndata: %s\n\n", id, b)
+	return err
+}
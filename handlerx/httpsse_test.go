@@ -0,0 +1,121 @@
+package handlerx
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestSubscriptionMapping() *Mapping {
+	m := newTestMapping()
+	m.operations["GET:/todos/events"] = "todoAdded"
+	m.selections["todoAdded"] = "{id,text,done}"
+	m.arguments["todoAdded"] = StringMap{}
+	m.SetRouteOptions(RouteOptionsMap{
+		"GET:/todos/events": {Subscription: true},
+	})
+	return m
+}
+
+func TestSSE(t *testing.T) {
+	m := newTestSubscriptionMapping()
+	assert.Equal(t, "subscription todoAdded { todoAdded{id,text,done} }", m.queries["GET:/todos/events"])
+	h := newTestMappingRouter(m, NewPreparedQueryCache(nil))
+
+	tests := []struct {
+		Name        string
+		LastEventID string
+		Expected    string
+	}{
+		{
+			Name: "new stream",
+			Expected: "id: 1\ndata: {\"code\":0,\"data\":{\"done\":false,\"id\":\"T1\",\"text\":\"buy milk\"}}\n\n" +
+				"id: 2\ndata: {\"code\":0,\"data\":{\"done\":true,\"id\":\"T2\",\"text\":\"walk dog\"}}\n\n" +
+				"id: 3\ndata: {\"code\":0,\"data\":{\"done\":false,\"id\":\"T3\",\"text\":\"feed cat\"}}\n\n",
+		},
+		{
+			Name:        "reconnect",
+			LastEventID: "2",
+			Expected:    "id: 3\ndata: {\"code\":0,\"data\":{\"done\":false,\"id\":\"T3\",\"text\":\"feed cat\"}}\n\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest("GET", "/todos/events", nil)
+			r.Header.Set("Accept", MediaTypeEventStream)
+			if tt.LastEventID != "" {
+				r.Header.Set("Last-Event-ID", tt.LastEventID)
+			}
+			h.ServeHTTP(w, r)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, MediaTypeEventStream, w.Header().Get("Content-Type"))
+			assert.Equal(t, "no-cache", w.Header().Get("Cache-Control"))
+			assert.Equal(t, tt.Expected, w.Body.String())
+		})
+	}
+
+	t.Run("no HEAD", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("OPTIONS", "/todos/events", nil))
+
+		assert.Equal(t, "GET, OPTIONS", w.Header().Get("Allow"))
+	})
+
+	t.Run("queries are still served by GET", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", "/todos/T1", nil))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"code":0,"data":{"done":false,"id":"T1","text":"buy milk"}}`, w.Body.String())
+	})
+}
+
+func TestSSEKeepAlive(t *testing.T) {
+	m := newTestSubscriptionMapping()
+	require.NoError(t, m.Prepare(testSchema))
+
+	es := newTestExecutableSchema().(*graphql.ExecutableSchemaMock)
+	es.ExecFunc = func(ctx context.Context) graphql.ResponseHandler {
+		sent := false
+		return func(ctx context.Context) *graphql.Response {
+			if sent {
+				return nil
+			}
+			sent = true
+			time.Sleep(50 * time.Millisecond)
+			return &graphql.Response{Data: []byte(`{"todoAdded":{"id":"T1"}}`)}
+		}
+	}
+	srv := handler.New(es)
+	srv.AddTransport(SSE{KeepAlivePingInterval: 10 * time.Millisecond})
+
+	r := chi.NewRouter()
+	m.Bind(ChiBinder{Router: r}, srv)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/todos/events", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), ": keepalive\n\n")
+	assert.Contains(t, w.Body.String(), "id: 1\ndata: {\"code\":0,\"data\":{\"id\":\"T1\"}}\n\n")
+}
+
+func TestSSEOperationType(t *testing.T) {
+	m := newTestSubscriptionMapping()
+	r := newRESTRequest("GET", "/todos/events?fields=id", "/todos/events", "")
+
+	query, err := m.convertHTTPRequestToGraphQLQuery(createResponseContext(context.Background()), r, &graphql.RawParams{}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "subscription todoAdded { todoAdded{id} }", query)
+}
@@ -0,0 +1,205 @@
+package handlerx
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/99designs/gqlgen/graphql"
+)
+
+const (
+	// IdempotencyKeyHeader is the request header of the idempotency key of a mutation route
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader is set on the responses replayed for a repeated idempotency key
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+)
+
+// idempotentResponse is the first response to a request with an idempotency key
+type idempotentResponse struct {
+	// fingerprint of the request, the method, the URL and the body
+	fingerprint string
+	status      int
+	header      http.Header
+	body        []byte
+}
+
+// SetIdempotencyStore sets the store of the responses of the idempotent routes, eg. `@http(idempotent: true)`,
+// keyed by their Idempotency-Key header within their route and the scope of their caller, see SetIdempotencyScope.
+// The default store is an in-memory LRU of 1000 responses, a store shared by all the instances of a service,
+// eg. on Redis, can be set instead.
+func (m *Mapping) SetIdempotencyStore(store graphql.Cache) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.idempotencyStore = store
+}
+
+// IdempotencyScope returns the scope of the idempotency keys of a request, ie. its caller, so that
+// the same key sent by two callers is two different keys
+type IdempotencyScope func(r *http.Request) string
+
+// SetIdempotencyScope sets the scope of the idempotency keys, the default scope is the Authorization
+// header of the requests. Services which authenticate their callers otherwise, eg. by a session
+// cookie or a client certificate, should scope the keys by their caller identity.
+func (m *Mapping) SetIdempotencyScope(scope IdempotencyScope) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.idempotencyScope = scope
+}
+
+// authorizationScope is the default IdempotencyScope
+func authorizationScope(r *http.Request) string {
+	return r.Header.Get("Authorization")
+}
+
+// idempotencyStoreKey is the key of the response to a request in the idempotency store, its idempotency
+// key within its route and scope, hashed so that the credentials of the scope are not stored
+func idempotencyStoreKey(route string, scope string, key string) string {
+	h := sha256.New()
+	h.Write([]byte(route + "\n" + scope + "\n" + key))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// isIdempotent reports whether a REST route honours the Idempotency-Key header
+func (m *Mapping) isIdempotent(routeKey string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.getRouteOptions(routeKey).Idempotent
+}
+
+// requestFingerprint identifies the payload of a request sent with an idempotency key
+func requestFingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(r.Method + " " + r.URL.RequestURI() + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// idempotent serves the requests of an idempotent route with an Idempotency-Key header once: the first
+// response is stored and replayed for the requests repeating the key with the same payload, a key
+// reused with another payload is rejected with 422, a key still in progress with 409, and a request
+// whose body can not be read with 400. Server errors are not stored, so that the request can be retried.
+func (m *Mapping) idempotent(route string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(IdempotencyKeyHeader)
+		if key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ctx := r.Context()
+		// the request ID is not stored, a replayed response gets the ID of its own request
+		responseCtx := createResponseContext(WithMapping(ctx, m))
+		setRequestID(responseCtx, w, r)
+		fail := func(code int, msg string) {
+			w.Header().Set("Content-Type", "application/json")
+			writeMappingError(responseCtx, w, true, "", &mappingError{code: code, msg: msg})
+		}
+
+		// a body which could not be read has no fingerprint, nothing is stored for it
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			fail(http.StatusBadRequest, "body could not be read: "+err.Error())
+			return
+		}
+		r.Body = ioutil.NopCloser(bytes.NewBuffer(body))
+		fingerprint := requestFingerprint(r, body)
+
+		m.mu.RLock()
+		store := m.idempotencyStore
+		scope := m.idempotencyScope
+		m.mu.RUnlock()
+		if scope == nil {
+			scope = authorizationScope
+		}
+		storeKey := idempotencyStoreKey(route, scope(r), key)
+
+		replay := func() bool {
+			v, ok := store.Get(ctx, storeKey)
+			if !ok {
+				return false
+			}
+
+			response := v.(*idempotentResponse)
+			if response.fingerprint != fingerprint {
+				fail(http.StatusUnprocessableEntity, IdempotencyKeyHeader+" reused with another request: "+key)
+				return true
+			}
+			for k, values := range response.header {
+				w.Header()[k] = values
+			}
+			w.Header().Set(IdempotentReplayedHeader, "true")
+			w.WriteHeader(response.status)
+			_, _ = w.Write(response.body)
+			return true
+		}
+
+		if replay() {
+			return
+		}
+		if _, loaded := m.idempotencyKeys.LoadOrStore(storeKey, true); loaded {
+			fail(http.StatusConflict, IdempotencyKeyHeader+" in progress: "+key)
+			return
+		}
+		defer m.idempotencyKeys.Delete(storeKey)
+		// the first request may have completed in between
+		if replay() {
+			return
+		}
+
+		// only the headers of the response are stored, not the CORS headers set for this request
+		before := w.Header().Clone()
+		rw := &idempotentResponseWriter{ResponseWriter: w}
+		next.ServeHTTP(rw, r)
+
+		if rw.status == 0 {
+			rw.status = http.StatusOK
+		}
+		if rw.status < http.StatusInternalServerError {
+			store.Add(ctx, storeKey, &idempotentResponse{
+				fingerprint: fingerprint,
+				status:      rw.status,
+				header:      headersAdded(before, w.Header()),
+				body:        rw.body.Bytes(),
+			})
+		}
+	})
+}
+
+// headersAdded returns the headers of after which are not in before
+func headersAdded(before http.Header, after http.Header) http.Header {
+	added := make(http.Header)
+	for k, values := range after {
+		if _, ok := before[k]; !ok {
+			added[k] = append([]string(nil), values...)
+		}
+	}
+	return added
+}
+
+// idempotentResponseWriter records the response to a request with an idempotency key
+type idempotentResponseWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (w *idempotentResponseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *idempotentResponseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
@@ -0,0 +1,160 @@
+package handlerx
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/99designs/gqlgen/graphql/handler/lru"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIdempotency(t *testing.T) {
+	m := newTestMapping()
+	m.SetRouteOptions(RouteOptionsMap{
+		"POST:/todos": {Idempotent: true, Status: http.StatusCreated},
+	})
+	store := lru.New(10)
+	m.SetIdempotencyStore(store)
+	h := newTestMappingRouter(m, NewPreparedQueryCache(nil))
+
+	postAs := func(authorization string, key string, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/todos", strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/json")
+		if authorization != "" {
+			r.Header.Set("Authorization", authorization)
+		}
+		if key != "" {
+			r.Header.Set(IdempotencyKeyHeader, key)
+		}
+		h.ServeHTTP(w, r)
+		return w
+	}
+	post := func(key string, body string) *httptest.ResponseRecorder {
+		return postAs("", key, body)
+	}
+	const body = `{"input":{"text":"buy milk","userId":"U1"}}`
+
+	t.Run("first response is replayed", func(t *testing.T) {
+		first := post("K1", body)
+		assert.Equal(t, http.StatusCreated, first.Code)
+		assert.Empty(t, first.Header().Get(IdempotentReplayedHeader))
+		_, ok := store.Get(context.Background(), idempotencyStoreKey("POST:/todos", "", "K1"))
+		assert.True(t, ok)
+
+		replayed := post("K1", body)
+		assert.Equal(t, http.StatusCreated, replayed.Code)
+		assert.Equal(t, "true", replayed.Header().Get(IdempotentReplayedHeader))
+		assert.Equal(t, "application/json", replayed.Header().Get("Content-Type"))
+		assert.Equal(t, first.Body.String(), replayed.Body.String())
+	})
+
+	t.Run("key reused with another payload", func(t *testing.T) {
+		w := post("K1", `{"input":{"text":"walk dog","userId":"U1"}}`)
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+		assert.JSONEq(t, `{"code":422,"message":"Idempotency-Key reused with another request: K1","data":null,"request_id":"test-request-id"}`, w.Body.String())
+	})
+
+	t.Run("key in progress", func(t *testing.T) {
+		m.idempotencyKeys.Store(idempotencyStoreKey("POST:/todos", "", "K2"), true)
+		defer m.idempotencyKeys.Delete(idempotencyStoreKey("POST:/todos", "", "K2"))
+
+		w := post("K2", body)
+		assert.Equal(t, http.StatusConflict, w.Code)
+	})
+
+	t.Run("body read error", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/todos", iotest.ErrReader(errors.New("connection reset")))
+		r.Header.Set("Content-Type", "application/json")
+		r.Header.Set(IdempotencyKeyHeader, "K4")
+		h.ServeHTTP(w, r)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.JSONEq(t, `{"code":400,"message":"body could not be read: connection reset","data":null,"request_id":"test-request-id"}`, w.Body.String())
+
+		_, ok := store.Get(context.Background(), idempotencyStoreKey("POST:/todos", "", "K4"))
+		assert.False(t, ok)
+		_, inProgress := m.idempotencyKeys.Load(idempotencyStoreKey("POST:/todos", "", "K4"))
+		assert.False(t, inProgress)
+
+		// the key is free for a retry
+		assert.Equal(t, http.StatusCreated, post("K4", body).Code)
+	})
+
+	t.Run("no key", func(t *testing.T) {
+		w := post("", body)
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.Empty(t, w.Header().Get(IdempotentReplayedHeader))
+	})
+
+	t.Run("routes not opted in", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("DELETE", "/todos/T1", nil)
+		r.Header.Set(IdempotencyKeyHeader, "K3")
+		h.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		_, ok := store.Get(context.Background(), idempotencyStoreKey("DELETE:/todos/{id}", "", "K3"))
+		assert.False(t, ok)
+	})
+
+	t.Run("keys are scoped by caller", func(t *testing.T) {
+		first := postAs("Bearer alice", "K4", body)
+		assert.Equal(t, http.StatusCreated, first.Code)
+
+		// the key of another caller is another key, whatever its payload
+		other := postAs("Bearer bob", "K4", `{"input":{"text":"walk dog","userId":"U2"}}`)
+		assert.Equal(t, http.StatusCreated, other.Code)
+		assert.Empty(t, other.Header().Get(IdempotentReplayedHeader))
+
+		replayed := postAs("Bearer alice", "K4", body)
+		assert.Equal(t, "true", replayed.Header().Get(IdempotentReplayedHeader))
+		assert.Equal(t, first.Body.String(), replayed.Body.String())
+	})
+
+	t.Run("custom scope", func(t *testing.T) {
+		m.SetIdempotencyScope(func(r *http.Request) string {
+			return r.Header.Get("X-Tenant")
+		})
+		defer m.SetIdempotencyScope(nil)
+
+		tenant := func(name string) *httptest.ResponseRecorder {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest("POST", "/todos", strings.NewReader(body))
+			r.Header.Set("Content-Type", "application/json")
+			r.Header.Set("X-Tenant", name)
+			r.Header.Set(IdempotencyKeyHeader, "K5")
+			h.ServeHTTP(w, r)
+			return w
+		}
+		assert.Empty(t, tenant("acme").Header().Get(IdempotentReplayedHeader))
+		assert.Empty(t, tenant("globex").Header().Get(IdempotentReplayedHeader))
+		assert.Equal(t, "true", tenant("acme").Header().Get(IdempotentReplayedHeader))
+	})
+
+	t.Run("batch items", func(t *testing.T) {
+		batch := func() *httptest.ResponseRecorder {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest("POST", "/batch", strings.NewReader(`[{"method": "POST", "path": "/todos", "body": `+body+`}]`))
+			r.Header.Set("Content-Type", "application/json")
+			r.Header.Set(IdempotencyKeyHeader, "K6")
+			h.ServeHTTP(w, r)
+			return w
+		}
+
+		// the items are not deduplicated, and do not take the key of the batch request
+		for i := 0; i < 2; i++ {
+			w := batch()
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Empty(t, w.Header().Get(IdempotentReplayedHeader))
+		}
+		_, ok := store.Get(context.Background(), idempotencyStoreKey("POST:/todos", "", "K6"))
+		assert.False(t, ok)
+		assert.Empty(t, post("K6", body).Header().Get(IdempotentReplayedHeader))
+	})
+}
@@ -0,0 +1,307 @@
+package handlerx
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Level is the severity of a log entry
+type Level int
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+func (l Level) String() string {
+	switch l {
+	case LevelDebug:
+		return "debug"
+	case LevelInfo:
+		return "info"
+	case LevelWarn:
+		return "warn"
+	default:
+		return "error"
+	}
+}
+
+// Logger is a structured, leveled logger. The fields of an entry are alternating keys and values, eg.
+//
+//	logger.Log(ctx, LevelInfo, "request", "request_id", "5f0c…", "route", "/todos/{id}", "status", 200)
+//
+// Every request served by the GET, POST, DELETE and SSE transports is logged once, with the fields
+// request_id, method, path, route, operation, rest, status, duration, error_codes and trace_id.
+// The server-sent event streams are logged when they are closed.
+type Logger interface {
+	Log(ctx context.Context, level Level, msg string, keyvals ...interface{})
+}
+
+// LoggerFunc adapts a function to a Logger
+type LoggerFunc func(ctx context.Context, level Level, msg string, keyvals ...interface{})
+
+func (f LoggerFunc) Log(ctx context.Context, level Level, msg string, keyvals ...interface{}) {
+	f(ctx, level, msg, keyvals...)
+}
+
+// PrinterLogger writes the entries of level and above to a Printer as logfmt lines, eg.
+// `level=info msg=request request_id=5f0c… route=/todos/{id} status=200`
+func PrinterLogger(printer Printer, level Level) Logger {
+	return LoggerFunc(func(ctx context.Context, l Level, msg string, keyvals ...interface{}) {
+		if l < level {
+			return
+		}
+		fields := []string{"level=" + l.String(), "msg=" + logfmtValue(msg)}
+		for i := 0; i+1 < len(keyvals); i += 2 {
+			fields = append(fields, fmt.Sprintf("%v=%s", keyvals[i], logfmtValue(keyvals[i+1])))
+		}
+		printer.Println(strings.Join(fields, " "))
+	})
+}
+
+func logfmtValue(v interface{}) string {
+	var s string
+	switch v := v.(type) {
+	case []string:
+		s = strings.Join(v, ",")
+	default:
+		s = fmt.Sprintf("%v", v)
+	}
+	if s == "" || strings.ContainsAny(s, " \"=") {
+		return fmt.Sprintf("%q", s)
+	}
+	return s
+}
+
+var (
+	_loggerMu sync.RWMutex
+	_logger   Logger
+)
+
+// RegisterLogger sets the logger of the transports, nothing is logged if nil
+func RegisterLogger(logger Logger) {
+	_loggerMu.Lock()
+	defer _loggerMu.Unlock()
+
+	_logger = logger
+}
+
+func logf(ctx context.Context, level Level, msg string, keyvals ...interface{}) {
+	_loggerMu.RLock()
+	logger := _logger
+	_loggerMu.RUnlock()
+
+	if logger != nil {
+		logger.Log(ctx, level, msg, keyvals...)
+	}
+}
+
+// RequestIDHeader is the header of the ID of a request, it is taken from the request or generated,
+// and returned in the response and in the REST error envelopes
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength limits the request IDs taken from the requests
+const maxRequestIDLength = 128
+
+var (
+	_requestIDGeneratorMu sync.RWMutex
+	_requestIDGenerator   = randomRequestID
+)
+
+// randomRequestID is the default request ID generator
+func randomRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
+
+// RegisterRequestIDGenerator sets how the IDs of the requests without an X-Request-ID header are generated,
+// they are 32 random hex digits by default, or if nil
+func RegisterRequestIDGenerator(generator func() string) {
+	_requestIDGeneratorMu.Lock()
+	defer _requestIDGeneratorMu.Unlock()
+
+	if generator == nil {
+		generator = randomRequestID
+	}
+	_requestIDGenerator = generator
+}
+
+func generateRequestID() string {
+	_requestIDGeneratorMu.RLock()
+	generator := _requestIDGenerator
+	_requestIDGeneratorMu.RUnlock()
+
+	return generator()
+}
+
+// validRequestID reports whether a request ID is safe to be logged and returned, ie. short printable ASCII
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range id {
+		if c < 0x21 || c > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// setRequestID takes the ID of a request from its X-Request-ID header, or generates it into the header,
+// and returns it in the response header and the response context
+func setRequestID(ctx context.Context, w http.ResponseWriter, r *http.Request) string {
+	id := r.Header.Get(RequestIDHeader)
+	if !validRequestID(id) {
+		id = generateRequestID()
+		r.Header.Set(RequestIDHeader, id)
+	}
+	w.Header().Set(RequestIDHeader, id)
+	if responseCtx := GetResponseContext(ctx); responseCtx != nil {
+		responseCtx.requestID = id
+	}
+	return id
+}
+
+// requestLog is the log entry, and the trace, of a request served by a transport
+type requestLog struct {
+	ctx   context.Context
+	r     *http.Request
+	w     *statusResponseWriter
+	start time.Time
+
+	isRESTful bool
+	// translate is the timing of the conversion of a REST request into a GraphQL query
+	translate graphql.TraceTiming
+	rc        *graphql.OperationContext
+	// created is when the operation context was created, or failed to be
+	created time.Time
+
+	// tracer and root span of a traced request, and whether the operation was executed
+	tracer   *Tracer
+	span     trace.Span
+	executed bool
+}
+
+// logRequest starts the log entry of a request, and its root span if traced. The transport sets
+// the timings of the request and logs it when done.
+func logRequest(ctx context.Context, w http.ResponseWriter, r *http.Request, tracer *Tracer) (*requestLog, context.Context, http.ResponseWriter) {
+	setRequestID(ctx, w, r)
+	sw := &statusResponseWriter{ResponseWriter: w}
+	l := &requestLog{ctx: ctx, r: r, w: sw, start: graphql.GetStartTime(ctx)}
+	if l.start.IsZero() {
+		l.start = time.Now()
+	}
+
+	if tracer != nil {
+		l.tracer = tracer
+		ctx, l.span = tracer.start(ctx, r, l.start)
+	}
+	return l, ctx, sw
+}
+
+// setOperation records the operation context of the request, as returned by CreateOperationContext
+func (l *requestLog) setOperation(rc *graphql.OperationContext) {
+	l.rc = rc
+	l.created = graphql.Now()
+}
+
+// operation returns the name of the operation of the request, or "" if not known
+func (l *requestLog) operation() string {
+	switch {
+	case l.rc == nil:
+		return ""
+	case l.rc.Operation != nil:
+		return l.rc.Operation.Name
+	default:
+		return l.rc.OperationName
+	}
+}
+
+// debug logs the GraphQL query of the request
+func (l *requestLog) debug(params *graphql.RawParams) {
+	logf(l.ctx, LevelDebug, "query", "request_id", l.w.Header().Get(RequestIDHeader), "method", l.r.Method,
+		"path", l.r.URL.Path, "query", params.Query, "variables", params.Variables)
+}
+
+func (l *requestLog) done() {
+	status := l.w.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	level := LevelInfo
+	switch {
+	case status >= http.StatusInternalServerError:
+		level = LevelError
+	case status >= http.StatusBadRequest:
+		level = LevelWarn
+	}
+
+	var route string
+	if l.isRESTful {
+		route, _, _ = requestRoute(l.r)
+	}
+	l.endTrace(route, status)
+
+	keyvals := []interface{}{
+		"request_id", l.w.Header().Get(RequestIDHeader),
+		"method", l.r.Method,
+		"path", l.r.URL.Path,
+	}
+	if l.isRESTful {
+		keyvals = append(keyvals, "route", route)
+	}
+	keyvals = append(keyvals,
+		"operation", l.operation(),
+		"rest", l.isRESTful,
+		"status", status,
+		"duration", time.Since(l.start),
+	)
+	if responseCtx := GetResponseContext(l.ctx); responseCtx != nil && len(responseCtx.errorCodes) > 0 {
+		keyvals = append(keyvals, "error_codes", responseCtx.errorCodes)
+	}
+	if l.span != nil && l.span.SpanContext().IsValid() {
+		keyvals = append(keyvals, "trace_id", l.span.SpanContext().TraceID().String())
+	}
+	logf(l.ctx, level, "request", keyvals...)
+}
+
+// statusResponseWriter records the status of a response
+type statusResponseWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusResponseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusResponseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush keeps the streamed responses streaming, see SSE and NDJSON
+func (w *statusResponseWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,192 @@
+package handlerx
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testLogEntry struct {
+	level  Level
+	msg    string
+	fields map[string]interface{}
+}
+
+type testLogger struct {
+	mu      sync.Mutex
+	entries []testLogEntry
+}
+
+func (l *testLogger) Log(ctx context.Context, level Level, msg string, keyvals ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	fields := make(map[string]interface{})
+	for i := 0; i+1 < len(keyvals); i += 2 {
+		fields[keyvals[i].(string)] = keyvals[i+1]
+	}
+	l.entries = append(l.entries, testLogEntry{level, msg, fields})
+}
+
+// requests returns the entries logged once per request
+func (l *testLogger) requests() []testLogEntry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	ret := make([]testLogEntry, 0)
+	for _, e := range l.entries {
+		if e.msg == "request" {
+			ret = append(ret, e)
+		}
+	}
+	return ret
+}
+
+type testPrinter struct {
+	lines []string
+}
+
+func (p *testPrinter) Println(v ...interface{}) {
+	p.lines = append(p.lines, fmt.Sprint(v...))
+}
+
+func (p *testPrinter) Printf(format string, v ...interface{}) {
+	p.lines = append(p.lines, fmt.Sprintf(format, v...))
+}
+
+func TestRequestID(t *testing.T) {
+	h := newTestRouter(NewPreparedQueryCache(nil))
+
+	t.Run("generated", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", "/todos/T1", nil))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "test-request-id", w.Header().Get(RequestIDHeader))
+		// successful responses are not changed
+		assert.JSONEq(t, `{"code":0,"data":{"done":false,"id":"T1","text":"buy milk"}}`, w.Body.String())
+	})
+
+	t.Run("taken from the request", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "/todos/missing", nil)
+		r.Header.Set(RequestIDHeader, "req-42")
+		h.ServeHTTP(w, r)
+
+		assert.Equal(t, "req-42", w.Header().Get(RequestIDHeader))
+		assert.JSONEq(t, `{"code":500,"message":"todo not found","data":null,"request_id":"req-42"}`, w.Body.String())
+	})
+
+	t.Run("invalid in the request", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "/todos/T1", nil)
+		r.Header.Set(RequestIDHeader, strings.Repeat("x", maxRequestIDLength+1))
+		h.ServeHTTP(w, r)
+
+		assert.Equal(t, "test-request-id", w.Header().Get(RequestIDHeader))
+	})
+
+	t.Run("default generator", func(t *testing.T) {
+		RegisterRequestIDGenerator(nil)
+		defer RegisterRequestIDGenerator(func() string { return "test-request-id" })
+
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", "/todos/T1", nil))
+		assert.Regexp(t, "^[0-9a-f]{32}$", w.Header().Get(RequestIDHeader))
+	})
+
+	t.Run("mapping error", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "/todos?fields=unknown", nil)
+		r.Header.Set(RequestIDHeader, "req-43")
+		h.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.JSONEq(t, `{"code":400,"message":"fields: unknown field \"unknown\"","data":null,"request_id":"req-43"}`, w.Body.String())
+	})
+}
+
+func TestLogger(t *testing.T) {
+	logger := &testLogger{}
+	RegisterLogger(logger)
+	defer RegisterLogger(nil)
+
+	h := newTestRouter(NewPreparedQueryCache(nil))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/todos/T1", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/todos/missing", nil)
+	r.Header.Set(RequestIDHeader, "req-42")
+	h.ServeHTTP(w, r)
+	require.Equal(t, http.StatusInternalServerError, w.Code)
+
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest("POST", "/query", strings.NewReader(`{"query":"query todo { todo(id: \"T1\") { id } }"}`))
+	r.Header.Set("Content-Type", "application/json")
+	gql := handler.New(newTestExecutableSchema())
+	gql.AddTransport(POST{})
+	gql.ServeHTTP(w, r)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	newTestMappingRouter(newTestSubscriptionMapping(), NewPreparedQueryCache(nil)).
+		ServeHTTP(w, httptest.NewRequest("GET", "/todos/events", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	entries := logger.requests()
+	require.Len(t, entries, 4)
+
+	ok := entries[0]
+	assert.Equal(t, LevelInfo, ok.level)
+	assert.Equal(t, "test-request-id", ok.fields["request_id"])
+	assert.Equal(t, "GET", ok.fields["method"])
+	assert.Equal(t, "/todos/T1", ok.fields["path"])
+	assert.Equal(t, "/todos/{id}", ok.fields["route"])
+	assert.Equal(t, true, ok.fields["rest"])
+	assert.Equal(t, http.StatusOK, ok.fields["status"])
+	assert.IsType(t, time.Duration(0), ok.fields["duration"])
+	assert.NotContains(t, ok.fields, "error_codes")
+
+	failed := entries[1]
+	assert.Equal(t, LevelError, failed.level)
+	assert.Equal(t, "req-42", failed.fields["request_id"])
+	assert.Equal(t, http.StatusInternalServerError, failed.fields["status"])
+	assert.Equal(t, []string{"NOT_FOUND"}, failed.fields["error_codes"])
+
+	graphqlEntry := entries[2]
+	assert.Equal(t, LevelInfo, graphqlEntry.level)
+	assert.Equal(t, "todo", graphqlEntry.fields["operation"])
+	assert.Equal(t, false, graphqlEntry.fields["rest"])
+	assert.NotContains(t, graphqlEntry.fields, "route")
+
+	// the event streams are logged when closed
+	stream := entries[3]
+	assert.Equal(t, LevelInfo, stream.level)
+	assert.Equal(t, "test-request-id", stream.fields["request_id"])
+	assert.Equal(t, "/todos/events", stream.fields["route"])
+	assert.Equal(t, "todoAdded", stream.fields["operation"])
+	assert.Equal(t, http.StatusOK, stream.fields["status"])
+}
+
+func TestPrinterLogger(t *testing.T) {
+	printer := &testPrinter{}
+	logger := PrinterLogger(printer, LevelInfo)
+
+	logger.Log(context.Background(), LevelDebug, "query", "query", "{ todos { id } }")
+	logger.Log(context.Background(), LevelWarn, "request", "request_id", "req-42", "route", "/todos/{id}",
+		"status", 404, "error_codes", []string{"NOT_FOUND", "400"})
+
+	assert.Equal(t, []string{`level=warn msg=request request_id=req-42 route=/todos/{id} status=404 error_codes=NOT_FOUND,400`}, printer.lines)
+}
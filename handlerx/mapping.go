@@ -2,46 +2,423 @@ package handlerx
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/99designs/gqlgen/graphql"
-	"github.com/go-chi/chi/v5"
+	"github.com/99designs/gqlgen/graphql/handler/lru"
+	"github.com/speedoops/go-gqlrest/scalar"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/parser"
+	"github.com/vektah/gqlparser/v2/validator"
 )
 
 // 1. Global Declaration
 type StringMap map[string]string
 type ArgTypeMap map[string]StringMap
 
-// 2. Local variables
-// REST URL => GraphQL Operation
-var restURL2GraphOperation StringMap
+// RouteOptions are the REST options of a route, read from its @http directive
+type RouteOptions struct {
+	// Paginate enables the standard paging parameters and response metadata of list routes
+	Paginate bool
+	// Status is the HTTP status of successful responses, 200 if not set
+	Status int
+	// Produces are the media types of the responses besides JSON, eg. "text/csv", chosen by the Accept header
+	Produces []string
+	// Stream writes the payloads of a subscription route as NDJSON instead of server-sent events, see NDJSON
+	Stream bool
+	// Subscription runs the route as a subscription, its payloads are streamed as server-sent events, see SSE, or as NDJSON with Stream
+	Subscription bool
+	// Idempotent honours the Idempotency-Key header of the requests to a mutation route, see SetIdempotencyStore
+	Idempotent bool
+	// StrictParams rejects the requests with parameters the operation does not take, see SetStrictParams
+	StrictParams bool
+}
+
+type RouteOptionsMap map[string]*RouteOptions
 
-// GraphQL Operation => Fields Selection
-var graphOperation2RESTSelection StringMap
+// 2. Mapping
+// Mapping is the REST to GraphQL mapping of one schema, it is built by the generated
+// RegisterHandlers and mounted on a router by Mapping.Bind. Several mappings,
+// eg. for a public and an internal schema, can be mounted on one server at different prefixes.
+type Mapping struct {
+	mu sync.RWMutex
 
-// GraphQL Operation => Operation Arguments Pair of <ArgName,ArgType>
-var restOperation2Arguments ArgTypeMap
-var inputType2FieldDefinitions ArgTypeMap
+	// REST URL => GraphQL Operation
+	operations StringMap
+	// GraphQL Operation => Fields Selection
+	selections StringMap
+	// GraphQL Operation => Operation Arguments Pair of <ArgName,ArgType>
+	arguments  ArgTypeMap
+	inputTypes ArgTypeMap
+	// Type Name => Type Kind
+	typeKinds StringMap
+	// Enum Name => Enum Values, read from the schema by Prepare
+	enumValues map[string][]string
+	// Operation Type and Name => Default Values of the Arguments, read from the schema by Prepare
+	argDefaults map[string]StringMap
+	// REST URL => GraphQL Query Document, with typed variables
+	queries StringMap
+	// REST URL => Route Options
+	routeOptions RouteOptionsMap
+	// Compiled Patterns of the REST URLs, in order, to match the paths of the batch items
+	routePatterns []*routePattern
+	// GraphQL Query Document => Parsed and Validated Document
+	documents map[string]*ast.QueryDocument
+	// Cross-Origin Policy of the REST routes
+	cors *CORSPolicy
+	// GraphQL Error => HTTP Status
+	errorMapper ErrorMapper
+	// Format of REST Errors
+	errorFormat ErrorFormat
+	// Media Type => Response Encoder
+	encoders map[string]Encoder
+	// Scalar Name => REST Value Conversion
+	scalars *scalar.Registry
+	// Route Pattern of the Batch Route, empty if not bound
+	batchPattern string
+	// Route, Scope and Idempotency-Key => First Response of Idempotent Routes
+	idempotencyStore graphql.Cache
+	// Route, Scope and Idempotency-Key => In Progress
+	idempotencyKeys sync.Map
+	// Scope of the Idempotency Keys, the Authorization Header if nil
+	idempotencyScope IdempotencyScope
+	// Reject Unknown Parameters of All Routes
+	strictParams bool
+}
 
-// Type Name => Type Kind
-var typeName2TypeKinds StringMap
+// MappingOption configures a Mapping when it is created, ie. before its operations are prepared,
+// eg. the options given to the generated RegisterHandlers
+type MappingOption func(*Mapping)
 
-func SetupHTTP2GraphQLMapping(operations StringMap, selections StringMap,
+func NewMapping(opts ...MappingOption) *Mapping {
+	m := &Mapping{
+		encoders:         defaultEncoders(),
+		scalars:          scalar.NewRegistry(),
+		idempotencyStore: lru.New(1000),
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// Setup sets the operations and types of the mapping, the documents prepared before are dropped
+func (m *Mapping) Setup(operations StringMap, selections StringMap,
 	arguments ArgTypeMap, inputTypes ArgTypeMap, typeKinds StringMap) {
-	restURL2GraphOperation = operations
-	graphOperation2RESTSelection = selections
-	restOperation2Arguments = arguments
-	inputType2FieldDefinitions = inputTypes
-	typeName2TypeKinds = typeKinds
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.operations = operations
+	m.selections = selections
+	m.arguments = arguments
+	m.inputTypes = inputTypes
+	m.typeKinds = typeKinds
+	m.buildQueries()
+}
+
+// SetRouteOptions sets the options of REST routes, routes without options use the defaults.
+// The documents prepared before are dropped, as the options may change the operation type of a route.
+func (m *Mapping) SetRouteOptions(options RouteOptionsMap) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.routeOptions = options
+	m.buildQueries()
+}
+
+// buildQueries compiles the query document and the route pattern of every REST route
+func (m *Mapping) buildQueries() {
+	m.queries = make(StringMap, len(m.operations))
+	m.routePatterns = make([]*routePattern, 0, len(m.operations))
+	for route, operationName := range m.operations {
+		m.queries[route] = m.buildGraphQLQuery(m.operationType(route), operationName, m.selections[operationName])
+		m.routePatterns = append(m.routePatterns, compileRoutePattern(route))
+	}
+	sort.Slice(m.routePatterns, func(i, j int) bool {
+		return m.routePatterns[i].route < m.routePatterns[j].route
+	})
+	m.documents = nil
+}
+
+// operationType returns the GraphQL operation type of a REST route: GET routes are queries,
+// unless they are subscriptions, and other methods are mutations
+func (m *Mapping) operationType(routeKey string) string {
+	switch {
+	case m.getRouteOptions(routeKey).Subscription:
+		return "subscription"
+	case strings.HasPrefix(routeKey, http.MethodGet+":"):
+		return "query"
+	default:
+		return "mutation"
+	}
+}
+
+// getRouteOptions returns the options of a REST route
+func (m *Mapping) getRouteOptions(routeKey string) *RouteOptions {
+	if options, ok := m.routeOptions[routeKey]; ok && options != nil {
+		return options
+	}
+	return &RouteOptions{}
+}
+
+// Prepare parses and validates the query document of every REST route
+// against the schema once, so that requests can reuse it instead of parsing it again. The variables
+// of the arguments with a default value in the schema are given it, so that the requests can leave them out.
+// It returns an error listing every route whose generated query is not valid.
+func (m *Mapping) Prepare(schema *ast.Schema) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	routes := make([]string, 0, len(m.queries))
+	for route := range m.queries {
+		routes = append(routes, route)
+	}
+	sort.Strings(routes)
+
+	// the variables of the arguments with a default value get it, so that they can be left out
+	m.argDefaults = make(map[string]StringMap)
+	for operationType, def := range map[string]*ast.Definition{"query": schema.Query, "mutation": schema.Mutation, "subscription": schema.Subscription} {
+		if def == nil {
+			continue
+		}
+		for _, field := range def.Fields {
+			for _, arg := range field.Arguments {
+				if arg.DefaultValue == nil {
+					continue
+				}
+				if m.argDefaults[operationType+":"+field.Name] == nil {
+					m.argDefaults[operationType+":"+field.Name] = make(StringMap)
+				}
+				m.argDefaults[operationType+":"+field.Name][arg.Name] = arg.DefaultValue.String()
+			}
+		}
+	}
+	m.buildQueries()
+
+	m.enumValues = make(map[string][]string)
+	for name, def := range schema.Types {
+		if def.Kind == ast.Enum {
+			values := make([]string, 0, len(def.EnumValues))
+			for _, value := range def.EnumValues {
+				values = append(values, value.Name)
+			}
+			m.enumValues[name] = values
+		}
+	}
+
+	documents := make(map[string]*ast.QueryDocument, len(routes))
+	msgs := make([]string, 0)
+	for _, route := range routes {
+		query := m.queries[route]
+		doc, err := parser.ParseQuery(&ast.Source{Input: query})
+		if err != nil {
+			msgs = append(msgs, fmt.Sprintf("%s: %s", route, err.Error()))
+			continue
+		}
+		if errs := validator.Validate(schema, doc); len(errs) > 0 {
+			msgs = append(msgs, fmt.Sprintf("%s: %s", route, errs.Error()))
+			continue
+		}
+		documents[query] = doc
+	}
+	for _, route := range routes {
+		if err := m.checkSortArgument(m.arguments[m.operations[route]]); err != nil {
+			msgs = append(msgs, fmt.Sprintf("%s: %s", route, err.Error()))
+		}
+		if routeOptions := m.getRouteOptions(route); routeOptions.Stream && !routeOptions.Subscription {
+			msgs = append(msgs, fmt.Sprintf("%s: only subscriptions can be streamed", route))
+		}
+		for _, mediaType := range m.getRouteOptions(route).Produces {
+			if _, ok := m.encoders[mediaType]; !ok && mediaType != MediaTypeJSON {
+				msgs = append(msgs, fmt.Sprintf("%s: no encoder for media type %q", route, mediaType))
+			}
+		}
+	}
+
+	if len(msgs) > 0 {
+		return errors.New("mapping: invalid REST operations:\n" + strings.Join(msgs, "\n"))
+	}
+
+	m.documents = documents
+	return nil
+}
+
+// preparedDocument returns the document prepared for a query, if any
+func (m *Mapping) preparedDocument(query string) (*ast.QueryDocument, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	doc, ok := m.documents[query]
+	return doc, ok
+}
+
+// Routes returns the REST routes of the mapping, as "METHOD:/path" keys
+func (m *Mapping) Routes() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	routes := make([]string, 0, len(m.operations))
+	for route := range m.operations {
+		routes = append(routes, route)
+	}
+	sort.Strings(routes)
+	return routes
+}
+
+// isSubscription reports whether a REST route runs a subscription
+func (m *Mapping) isSubscription(routeKey string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.getRouteOptions(routeKey).Subscription
+}
+
+// streamMediaType returns the media type the payloads of a subscription route are streamed as,
+// or "" if the route is not a subscription
+func (m *Mapping) streamMediaType(routeKey string) string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	routeOptions := m.getRouteOptions(routeKey)
+	switch {
+	case !routeOptions.Subscription:
+		return ""
+	case routeOptions.Stream:
+		return MediaTypeNDJSON
+	default:
+		return MediaTypeEventStream
+	}
+}
+
+// Bind mounts every REST route of the mapping with binder, each served by srv. GET routes
+// but subscriptions also answer HEAD, and every route pattern answers OPTIONS with the methods registered for it.
+func (m *Mapping) Bind(binder RouteBinder, srv http.Handler) {
+	h := m.Handler(srv)
+	options := m.optionsHandler()
+
+	routes := m.Routes()
+	bound := make(map[string]bool, len(routes))
+	bind := func(method string, pattern string, handler http.Handler) {
+		if !bound[method+":"+pattern] {
+			bound[method+":"+pattern] = true
+			binder.Bind(method, pattern, withBoundRoute(pattern, binder, handler))
+		}
+	}
+	for _, route := range routes {
+		kv := strings.SplitN(route, ":", 2)
+		if kv[0] != http.MethodGet && m.isIdempotent(route) {
+			bind(kv[0], kv[1], m.Handler(m.idempotent(route, srv)))
+			continue
+		}
+		bind(kv[0], kv[1], h)
+	}
+	for _, route := range routes {
+		kv := strings.SplitN(route, ":", 2)
+		if kv[0] == http.MethodGet && !m.isSubscription(route) {
+			bind(http.MethodHead, kv[1], h)
+		}
+		bind(http.MethodOptions, kv[1], options)
+	}
+}
+
+// Handler makes the mapping available to the REST transports of the requests served by next
+func (m *Mapping) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m.writeCORSHeaders(w, r)
+		next.ServeHTTP(w, r.WithContext(WithMapping(r.Context(), m)))
+	})
+}
+
+type mappingContextType string
+
+var mappingContextKey mappingContextType = "gogqlrest_mapping"
+
+// WithMapping returns a context carrying the mapping used to convert REST requests
+func WithMapping(ctx context.Context, m *Mapping) context.Context {
+	return context.WithValue(ctx, mappingContextKey, m)
+}
+
+// GetMapping returns the mapping carried by the context, or nil
+func GetMapping(ctx context.Context) *Mapping {
+	if v, ok := ctx.Value(mappingContextKey).(*Mapping); ok {
+		return v
+	}
+
+	return nil
 }
 
-func convertHTTPRequestToGraphQLQuery(r *http.Request, params *graphql.RawParams, body []byte) (string, error) {
-	// DbgPrintf(r, "ADE: http.POST: %#v", r.URL.Path)
-	// DbgPrintf(r, "ADE: http.POST: %#v", r.URL.Query())
+// requestMapping returns the mapping of a transport, or the one carried by the request
+func requestMapping(m *Mapping, r *http.Request) *Mapping {
+	if m != nil {
+		return m
+	}
+	return GetMapping(r.Context())
+}
+
+// buildGraphQLQuery compiles one fixed operation for a REST route, eg.
+// "query todos($ids:[ID!]) { todos(ids:$ids){id,text} }". Request values are
+// only ever passed in as variables, so the document does not vary per request.
+func (m *Mapping) buildGraphQLQuery(operationType string, operationName string, selection string) string {
+	argTypes := m.arguments[operationName]
+	argNames := make([]string, 0, len(argTypes))
+	for k := range argTypes {
+		argNames = append(argNames, k)
+	}
+	sort.Strings(argNames)
+
+	variables := make([]string, 0, len(argNames))
+	arguments := make([]string, 0, len(argNames))
+	for _, k := range argNames {
+		variable := "$" + k + ":" + argTypes[k]
+		if defaultValue, ok := m.argDefaults[operationType+":"+operationName][k]; ok {
+			// a required argument with a default value can be left out
+			variable += "=" + defaultValue
+		}
+		variables = append(variables, variable)
+		arguments = append(arguments, k+":$"+k)
+	}
+
+	queryString := operationType + " " + operationName
+	if len(variables) > 0 {
+		queryString += "(" + strings.Join(variables, ",") + ")"
+	}
+	queryString += " { " + operationName
+	if len(arguments) > 0 {
+		queryString += "(" + strings.Join(arguments, ",") + ")"
+	}
+	queryString += selection
+	queryString += " }"
+
+	return queryString
+}
+
+// mappingError is a REST request rejected by the mapping, with the status to report it with
+type mappingError struct {
+	code int
+	msg  string
+	// errs are reported one by one if set, eg. one per invalid parameter
+	errs paramErrors
+}
+
+func (e *mappingError) Error() string {
+	return e.msg
+}
+
+func (m *Mapping) convertHTTPRequestToGraphQLQuery(ctx context.Context, r *http.Request, params *graphql.RawParams, body []byte) (string, error) {
+	if m == nil {
+		return "", errors.New("unknown operation: no REST mapping for " + r.URL.Path)
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
 	var bodyParams map[string]interface{}
 	if len(body) > 0 {
 		bodyReader := ioutil.NopCloser(bytes.NewBuffer(body))
@@ -52,87 +429,178 @@ func convertHTTPRequestToGraphQLQuery(r *http.Request, params *graphql.RawParams
 		bodyParams = make(map[string]interface{})
 	}
 
-	queryString := "mutation { "
-	if r.Method == "GET" {
-		queryString = "query { "
-	}
-
 	// 1. Operation Name
-	rctx := chi.RouteContext(r.Context())
-	routePattern := rctx.RoutePattern()
-	operationName, ok := restURL2GraphOperation[r.Method+":"+routePattern]
+	routePattern, pathKeys, pathValues := requestRoute(r)
+	method := r.Method
+	if method == http.MethodHead {
+		// HEAD runs the query of the GET route, the transport drops the body
+		method = http.MethodGet
+	}
+	routeKey := method + ":" + routePattern
+	operationName, ok := m.operations[routeKey]
 	if !ok {
-		err := errors.New("unknown operation: " + rctx.RoutePattern())
+		err := errors.New("unknown operation: " + routePattern)
 		return "", err
 	}
-	queryString += operationName // eg. "query { todos"
 
-	// 2. Query Parameters
-	if argTypes, ok := restOperation2Arguments[operationName]; ok {
+	// 2. Field Selection
+	queryString, ok := m.queries[routeKey]
+	if !ok {
+		panic("OOPS! no matching field selection for " + routePattern)
+	}
+
+	routeOptions := m.getRouteOptions(routeKey)
+	if responseCtx := GetResponseContext(ctx); responseCtx != nil {
+		responseCtx.status = routeOptions.Status
+		responseCtx.url = r.URL
+	}
+
+	// 1.1 Content Negotiation, subscriptions are always streamed as server-sent events or NDJSON
+	if !routeOptions.Subscription {
+		offers := append([]string{MediaTypeJSON}, routeOptions.Produces...)
+		mediaType := negotiateMediaType(r.Header.Get("Accept"), offers)
+		if mediaType == "" {
+			return "", &mappingError{code: http.StatusNotAcceptable, msg: "not acceptable: " + r.Header.Get("Accept")}
+		}
+		if responseCtx := GetResponseContext(ctx); responseCtx != nil && mediaType != MediaTypeJSON {
+			responseCtx.mediaType = mediaType
+			responseCtx.encoder = m.encoders[mediaType]
+		}
+	}
+
+	// 2.1 Sparse Fieldsets, unless the operation takes an argument of the same name
+	argTypes, hasArgs := m.arguments[operationName]
+	urlQuery := r.URL.Query()
+	if _, ok := argTypes[fieldsParam]; !ok && urlQuery.Get(fieldsParam) != "" {
+		selection, err := narrowSelection(m.selections[operationName], strings.Join(urlQuery[fieldsParam], ","))
+		if err != nil {
+			return "", &mappingError{code: http.StatusBadRequest, msg: fieldsParam + ": " + err.Error()}
+		}
+		queryString = m.buildGraphQLQuery(m.operationType(routeKey), operationName, selection)
+		urlQuery.Del(fieldsParam)
+	}
+
+	// 2.2 Paging Parameters
+	if routeOptions.Paginate {
+		pagination, err := parsePagination(urlQuery)
+		if err != nil {
+			return "", &mappingError{code: http.StatusBadRequest, msg: err.Error()}
+		}
+		if responseCtx := GetResponseContext(ctx); responseCtx != nil {
+			responseCtx.pagination = pagination
+		}
+	}
+
+	// 2.3 Sorting and Filtering Parameters
+	filterValue, err := m.parseFilterParams(argTypes, urlQuery)
+	if err != nil {
+		return "", &mappingError{code: http.StatusBadRequest, msg: err.Error()}
+	}
+	var sortValue interface{}
+	if argType, ok := argTypes[sortParam]; ok && urlQuery.Get(sortParam) != "" {
+		if sortValue, err = m.parseSortParam(argType, strings.Join(urlQuery[sortParam], ",")); err != nil {
+			return "", &mappingError{code: http.StatusBadRequest, msg: err.Error()}
+		}
+		urlQuery.Del(sortParam)
+	}
+
+	// 2.4 Nested Parameters, eg. "input.network.vlan=10" or "items[0].name=x"
+	nestedParams, nestedInputParams, err := m.parseNestedParams(argTypes, urlQuery)
+	if err != nil {
+		return "", &mappingError{code: http.StatusBadRequest, msg: err.Error()}
+	}
+
+	// 2.5 Unknown Parameters, rejected in strict mode, otherwise dropped
+	if unknown, accepted := m.unknownParams(argTypes, urlQuery, bodyParams, routeOptions); len(unknown) > 0 {
+		msg := unknownParamsMessage(unknown, accepted)
+		if m.strictParams || routeOptions.StrictParams {
+			return "", &mappingError{code: http.StatusBadRequest, msg: msg}
+		}
+		dbgPrintf("HTTP %s %s: %s, dropped", r.Method, r.URL.Path, msg)
+	}
+
+	// 3. Query Parameters
+	variables := make(map[string]interface{})
+	if hasArgs {
 		queryParams := make(map[string]interface{})
 		inputParams := make(map[string]interface{})
-		// 2.1 Query Parameters (GET/POST/PUT/DELETE)
-		for k, v := range r.URL.Query() {
+		if filterValue != nil {
+			queryParams[filterParam] = filterValue
+		}
+		if sortValue != nil {
+			queryParams[sortParam] = sortValue
+		}
+		// names and locations of the parameters, for the errors
+		sources := make(map[string]paramSource)
+		inputSources := make(map[string]paramSource)
+		// 3.1 Query Parameters (GET/POST/PUT/DELETE)
+		for k, v := range urlQuery {
 			// convert "k=v1&k=v2&k=v3" to "k=v1,v2,v3"
 			val := strings.Join(v, ",")
 			inputParams[k] = val
 			queryParams[k] = val
+			sources[k] = paramSource{k, paramInQuery}
+			inputSources[k] = paramSource{k, paramInQuery}
 		}
-		// 2.2 Path Parameters (GET/POST/PUT/DELETE)
-		for i, k := range rctx.URLParams.Keys {
-			v := rctx.URLParams.Values[i]
-			// if strings.HasPrefix(v, "input.") {
-			// 	v = strings.Replace(v, "input.", "", 1)
-			// 	inputParams[k] = v
-			// } else {
-			// 	queryParams[k] = v
-			// }
+		for k, v := range nestedParams {
+			queryParams[k] = v
+			sources[k] = paramSource{k, paramInQuery}
+		}
+		for k, v := range nestedInputParams {
+			inputParams[k] = v
+			inputSources[k] = paramSource{k, paramInQuery}
+		}
+		// 3.2 Path Parameters (GET/POST/PUT/DELETE)
+		for i, k := range pathKeys {
+			v := pathValues[i]
 			inputParams[k] = v
 			queryParams[k] = v
+			sources[k] = paramSource{k, paramInPath}
+			inputSources[k] = paramSource{k, paramInPath}
 		}
-		// 2.3 Body Parameters (POST/PUT)
+		// 3.3 Body Parameters (POST/PUT)
 		for k, v := range bodyParams {
 			if k == "input" {
 				innerParams, _ := v.(map[string]interface{})
 				for ik, iv := range innerParams {
 					inputParams[ik] = iv
+					inputSources[ik] = paramSource{"input." + ik, paramInBody}
 				}
 				continue
 			}
 			inputParams[k] = v
 			queryParams[k] = v
+			sources[k] = paramSource{k, paramInBody}
+			inputSources[k] = paramSource{k, paramInBody}
 		}
 
 		if len(inputParams) > 0 {
 			queryParams["input"] = inputParams
 		}
 
-		queryParamsString := make([]string, 0)
+		errs := paramErrors{}
 		for k, v := range queryParams {
-			if paramKV, err := formatInputsToGraphQL(argTypes, k, v); err != nil {
+			paramValue, ok, err := m.formatInputsToGraphQL(argTypes, k, v)
+			if argErrs, isParamErr := asParamErrors(err, ""); isParamErr {
+				errs = append(errs, argErrs...)
+				continue
+			}
+			if err != nil {
 				return "", err
-			} else {
-				queryParamsString = append(queryParamsString, paramKV)
+			}
+			if ok {
+				variables[k] = paramValue
 			}
 		}
-		if len(queryParamsString) > 0 {
-			queryParamsStringX := strings.Join(queryParamsString, ",")
-			queryString += "(" + queryParamsStringX + ")" // eg. "query { todos(ids:[\"T9527\"],)"
+		if len(errs) > 0 {
+			errs = errs.locate(sources, inputSources)
+			return "", &mappingError{code: http.StatusBadRequest, msg: errs.Error(), errs: errs}
 		}
 	}
 
-	// 3. Field Selection
-	selection, ok := graphOperation2RESTSelection[operationName]
-	if !ok {
-		panic("OOPS! no matching field selection for " + rctx.RoutePattern())
-	}
-	queryString += selection // eg. "query { todos(ids:[\"T9527\"],){id,text,done,user{id}}"
-
-	// end of query or mutation
-	queryString += " }" // eg. "query { todos(ids:[\"T9527\"],){id,text,done,user{id}} }"
-
-	// DbgPrintf(r, "ADE:http.POST: %s", queryString)
 	params.Query = queryString
+	params.OperationName = operationName
+	params.Variables = variables
 
 	return queryString, nil
 }
@@ -165,37 +633,51 @@ func getSliceInterface(v interface{}) ([]interface{}, error) {
 	return nil, fmt.Errorf("mapping: require slice but got %#v", v)
 }
 
-func formatInputsToGraphQL(argTypes StringMap, k string, v interface{}) (string, error) {
+// formatInputsToGraphQL converts a REST parameter into the value of the GraphQL
+// variable with the same name, ok is false if the operation has no such argument.
+func (m *Mapping) formatInputsToGraphQL(argTypes StringMap, k string, v interface{}) (interface{}, bool, error) {
 	argType, ok := argTypes[k]
 	if !ok {
 		//dbgPrintf("ignore param %v %v=%v", argTypes, k, v)
-		return "", nil
+		return nil, false, nil
 	}
 	isArray, underlayingType := getUnderlayingArgType(argType)
 
 	if !isArray {
 		// 非数组比较简单，就是 k:v
-		if tmp, err := formatArgValueToGraphQL(underlayingType, k, v); err != nil {
-			return "", err
-		} else {
-			return fmt.Sprintf(`%s:%s`, k, tmp), nil
+		tmp, err := m.formatArgValueToGraphQL(underlayingType, k, v)
+		if err != nil {
+			return nil, false, err
 		}
+		return tmp, true, nil
 	}
 
 	// 数组类型比较麻烦，k:[v,v] 或 k:["v","v"]
 	vars, err := getSliceInterface(v)
 	if err != nil {
-		return "", err
+		return nil, false, newParamError(k, argType, v, "")
 	}
-	var vals []string
-	for _, vv := range vars {
-		if tmp, err := formatArgValueToGraphQL(underlayingType, k, vv); err != nil {
-			return "", err
-		} else {
-			vals = append(vals, tmp)
+	vals := make([]interface{}, 0, len(vars))
+	errs := paramErrors{}
+	for i, vv := range vars {
+		tmp, err := m.formatArgValueToGraphQL(underlayingType, k, vv)
+		if itemErrs, ok := asParamErrors(err, ""); ok {
+			// the errors of the items are reported with their index, eg. "ids[1]"
+			for _, e := range itemErrs {
+				e.path = append([]string{e.path[0], "[" + strconv.Itoa(i) + "]"}, e.path[1:]...)
+			}
+			errs = append(errs, itemErrs...)
+			continue
 		}
+		if err != nil {
+			return nil, false, err
+		}
+		vals = append(vals, tmp)
+	}
+	if len(errs) > 0 {
+		return nil, false, errs
 	}
-	return fmt.Sprintf(`%s:[%s]`, k, strings.Join(vals, ",")), nil
+	return vals, true, nil
 }
 
 func getUnderlayingArgType(argType string) (bool, string) {
@@ -208,42 +690,66 @@ func getUnderlayingArgType(argType string) (bool, string) {
 	return isArray, argType
 }
 
-func formatArgValueToGraphQL(underlayingType string, k string, v interface{}) (string, error) {
-	switch underlayingType {
-	case "Boolean", "Int", "Float":
-		return fmt.Sprintf(`%v`, v), nil
-	case "ID", "String", "Time", "IP", "IPRange", "MAC": // TODO: 新增基于string的scalar类型时，这里必须同步添加
-		return fmt.Sprintf("%q", v), nil
-	default:
-		if typeKind, ok := typeName2TypeKinds[underlayingType]; ok {
-			if typeKind == "ENUM" {
-				// 校验枚举值,非空字符串
-				str, ok := v.(string)
-				if !ok || str == "" {
-					return "", fmt.Errorf("expected type %v, found %v", underlayingType, v)
-				}
-				return fmt.Sprintf(`%v`, v), nil
+// formatArgValueToGraphQL converts a single REST value into a GraphQL variable value.
+// Query and path parameters always arrive as strings, so they are coerced here
+// according to the declared type, body parameters are already typed by the JSON decoder.
+// Values which do not have the declared type are reported as paramErrors.
+func (m *Mapping) formatArgValueToGraphQL(underlayingType string, k string, v interface{}) (interface{}, error) {
+	if v == nil {
+		// null is checked against the nullability of the type by the GraphQL validation
+		return nil, nil
+	}
+
+	if s, ok := m.scalar(underlayingType); ok {
+		value, err := s.Convert(v)
+		if err != nil {
+			reason := err.Error()
+			if errors.Is(err, scalar.ErrInvalid) {
+				reason = ""
+			}
+			return nil, newParamError(k, underlayingType, v, reason)
+		}
+		return value, nil
+	}
+
+	if typeKind, ok := m.typeKinds[underlayingType]; ok {
+		if typeKind == "ENUM" {
+			// 校验枚举值,非空字符串
+			str, ok := v.(string)
+			if !ok || str == "" {
+				return nil, newParamError(k, underlayingType, v, "")
 			}
+			return str, nil
+		}
 
-			if typeKind == "INPUT_OBJECT" {
-				if inputTypes, ok := inputType2FieldDefinitions[underlayingType]; ok {
-					queryParamsString := make([]string, 0)
-					queryParams, _ := v.(map[string]interface{})
-					for k, v := range queryParams {
-						if paramKV, err := formatInputsToGraphQL(inputTypes, k, v); err != nil {
-							return "", err
-						} else {
-							queryParamsString = append(queryParamsString, paramKV)
-						}
+		if typeKind == "INPUT_OBJECT" {
+			if inputTypes, ok := m.inputTypes[underlayingType]; ok {
+				queryParams, ok := v.(map[string]interface{})
+				if !ok {
+					return nil, newParamError(k, underlayingType, v, "")
+				}
+				inputParams := make(map[string]interface{})
+				errs := paramErrors{}
+				for ik, iv := range queryParams {
+					paramValue, ok, err := m.formatInputsToGraphQL(inputTypes, ik, iv)
+					if fieldErrs, isParamErr := asParamErrors(err, k); isParamErr {
+						errs = append(errs, fieldErrs...)
+						continue
 					}
-					if len(queryParamsString) > 0 {
-						queryParamsStringX := strings.Join(queryParamsString, ",")
-						return fmt.Sprintf(`{%s}`, queryParamsStringX), nil
+					if err != nil {
+						return nil, err
 					}
+					if ok {
+						inputParams[ik] = paramValue
+					}
+				}
+				if len(errs) > 0 {
+					return nil, errs
 				}
+				return inputParams, nil
 			}
 		}
 	}
 
-	return "", fmt.Errorf("mapping: unknown argument type %#v", v)
+	return nil, fmt.Errorf("mapping: unknown argument type %#v", v)
 }
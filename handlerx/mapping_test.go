@@ -1 +1,184 @@
 package handlerx
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vektah/gqlparser/v2"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/validator"
+)
+
+func newTestMapping(opts ...MappingOption) *Mapping {
+	m := NewMapping(opts...)
+	m.Setup(
+		StringMap{
+			"GET:/todos":         "todos",
+			"GET:/todos/{id}":    "todo",
+			"POST:/todos":        "createTodo",
+			"DELETE:/todos/{id}": "deleteTodo",
+			"GET:/hosts":         "hosts",
+			"PUT:/hosts/{id}":    "updateHost",
+		},
+		StringMap{
+			"todos":      "{id,text,done}",
+			"todo":       "{id,text,done}",
+			"createTodo": "{id,text,done}",
+			"deleteTodo": "",
+			"hosts":      "{id,name,status}",
+			"updateHost": "{id,name,status}",
+		},
+		ArgTypeMap{
+			"todos":      {"ids": "[ID!]", "limit": "Int", "done": "Boolean", "state": "TodoState"},
+			"todo":       {"id": "ID!"},
+			"createTodo": {"input": "NewTodoInput!"},
+			"deleteTodo": {"id": "ID!"},
+			"hosts":      {"filter": "HostFilter", "sort": "[HostSort!]"},
+			"updateHost": {"input": "UpdateHostInput!"},
+		},
+		ArgTypeMap{
+			"NewTodoInput":    {"text": "String!", "userId": "ID!", "priority": "Int"},
+			"HostFilter":      {"name": "String", "status": "StringFilter"},
+			"StringFilter":    {"eq": "String", "in": "[String!]"},
+			"HostSort":        {"name": "SortOrder", "created": "SortOrder"},
+			"UpdateHostInput": {"id": "ID!", "network": "NetworkInput", "disks": "[DiskInput!]", "tags": "[String!]"},
+			"NetworkInput":    {"vlan": "Int", "ip": "String"},
+			"DiskInput":       {"name": "String!", "size": "Int"},
+		},
+		StringMap{
+			"TodoState":       "ENUM",
+			"SortOrder":       "ENUM",
+			"NewTodoInput":    "INPUT_OBJECT",
+			"HostFilter":      "INPUT_OBJECT",
+			"StringFilter":    "INPUT_OBJECT",
+			"HostSort":        "INPUT_OBJECT",
+			"UpdateHostInput": "INPUT_OBJECT",
+			"NetworkInput":    "INPUT_OBJECT",
+			"DiskInput":       "INPUT_OBJECT",
+		},
+	)
+	m.SetRouteOptions(RouteOptionsMap{
+		"GET:/todos": {Paginate: true},
+	})
+	return m
+}
+
+func newRESTRequest(method string, target string, routePattern string, body string) *http.Request {
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+
+	rctx := chi.NewRouteContext()
+	rctx.RoutePatterns = []string{routePattern}
+	for i, part := range strings.Split(routePattern, "/") {
+		if strings.HasPrefix(part, "{") {
+			rctx.URLParams.Add(strings.Trim(part, "{}"), strings.Split(r.URL.Path, "/")[i])
+		}
+	}
+
+	return r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, rctx))
+}
+
+func TestConvertHTTPRequestToGraphQLQuery(t *testing.T) {
+	m := newTestMapping()
+
+	t.Run("query parameters are passed as variables", func(t *testing.T) {
+		r := newRESTRequest("GET", "/todos?ids=T1&ids=T2&limit=10&done=true&state=DONE", "/todos", "")
+		params := &graphql.RawParams{}
+
+		query, err := m.convertHTTPRequestToGraphQLQuery(context.Background(), r, params, nil)
+		require.NoError(t, err)
+		assert.Equal(t, "query todos($done:Boolean,$ids:[ID!],$limit:Int,$state:TodoState) "+
+			"{ todos(done:$done,ids:$ids,limit:$limit,state:$state){id,text,done} }", query)
+		assert.Equal(t, "todos", params.OperationName)
+		assert.Equal(t, map[string]interface{}{
+			"ids":   []interface{}{"T1", "T2"},
+			"limit": json.Number("10"),
+			"done":  true,
+			"state": "DONE",
+		}, params.Variables)
+	})
+
+	t.Run("parameter values never change the document", func(t *testing.T) {
+		r1 := newRESTRequest("GET", "/todos?state=DONE", "/todos", "")
+		r2 := newRESTRequest("GET", "/todos?state=DONE)%7B__schema%7Btypes%7Bname%7D%7D%7D", "/todos", "")
+
+		q1, err := m.convertHTTPRequestToGraphQLQuery(context.Background(), r1, &graphql.RawParams{}, nil)
+		require.NoError(t, err)
+		params := &graphql.RawParams{}
+		q2, err := m.convertHTTPRequestToGraphQLQuery(context.Background(), r2, params, nil)
+		require.NoError(t, err)
+
+		assert.Equal(t, q1, q2)
+		assert.Equal(t, "DONE){__schema{types{name}}}", params.Variables["state"])
+	})
+
+	t.Run("path and body parameters fill the input object", func(t *testing.T) {
+		r := newRESTRequest("POST", "/todos", "/todos", `{"text":"buy milk","userId":1,"unknown":"x"}`)
+		params := &graphql.RawParams{}
+
+		query, err := m.convertHTTPRequestToGraphQLQuery(context.Background(), r, params, []byte(`{"text":"buy milk","userId":1,"unknown":"x"}`))
+		require.NoError(t, err)
+		assert.Equal(t, "mutation createTodo($input:NewTodoInput!) { createTodo(input:$input){id,text,done} }", query)
+		assert.Equal(t, map[string]interface{}{
+			"input": map[string]interface{}{"text": "buy milk", "userId": "1"},
+		}, params.Variables)
+	})
+
+	t.Run("operation without selection", func(t *testing.T) {
+		r := newRESTRequest("DELETE", "/todos/T1", "/todos/{id}", "")
+		params := &graphql.RawParams{}
+
+		query, err := m.convertHTTPRequestToGraphQLQuery(context.Background(), r, params, nil)
+		require.NoError(t, err)
+		assert.Equal(t, "mutation deleteTodo($id:ID!) { deleteTodo(id:$id) }", query)
+		assert.Equal(t, "T1", params.Variables["id"])
+	})
+
+	t.Run("invalid boolean", func(t *testing.T) {
+		r := newRESTRequest("GET", "/todos?done=maybe", "/todos", "")
+
+		_, err := m.convertHTTPRequestToGraphQLQuery(context.Background(), r, &graphql.RawParams{}, nil)
+		assert.Error(t, err)
+	})
+
+	t.Run("unknown route", func(t *testing.T) {
+		r := newRESTRequest("GET", "/users", "/users", "")
+
+		_, err := m.convertHTTPRequestToGraphQLQuery(context.Background(), r, &graphql.RawParams{}, nil)
+		assert.Error(t, err)
+	})
+}
+
+func TestArgumentDefaultValue(t *testing.T) {
+	schema := gqlparser.MustLoadSchema(&ast.Source{Input: `
+		type Query {
+			items(limit: Int! = 10, offset: Int!): [String!]!
+		}
+	`})
+	m := NewMapping()
+	m.Setup(
+		StringMap{"GET:/items": "items"},
+		StringMap{"items": ""},
+		ArgTypeMap{"items": {"limit": "Int!", "offset": "Int!"}},
+		ArgTypeMap{},
+		StringMap{},
+	)
+	require.NoError(t, m.Prepare(schema))
+
+	query := m.queries["GET:/items"]
+	assert.Equal(t, "query items($limit:Int!=10,$offset:Int!) { items(limit:$limit,offset:$offset) }", query)
+
+	// the limit is left out and takes its default value
+	doc, ok := m.preparedDocument(query)
+	require.True(t, ok)
+	variables, err := validator.VariableValues(schema, doc.Operations[0], map[string]interface{}{"offset": 0})
+	require.Nil(t, err)
+	assert.Equal(t, int64(10), variables["limit"])
+}
@@ -0,0 +1,113 @@
+package handlerx
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+)
+
+// encodeMsgPack writes a JSON value as MessagePack, see https://github.com/msgpack/msgpack/blob/master/spec.md.
+// Only the types of JSON values are needed, map keys are sorted so that the output is stable.
+func encodeMsgPack(w io.Writer, v interface{}) error {
+	buf := appendMsgPack(nil, plainValue(v))
+	_, err := w.Write(buf)
+	return err
+}
+
+func appendMsgPack(buf []byte, v interface{}) []byte {
+	switch vv := v.(type) {
+	case nil:
+		return append(buf, 0xc0)
+	case bool:
+		if vv {
+			return append(buf, 0xc3)
+		}
+		return append(buf, 0xc2)
+	case int64:
+		return appendMsgPackInt(buf, vv)
+	case float64:
+		buf = append(buf, 0xcb)
+		return appendUint64(buf, math.Float64bits(vv))
+	case string:
+		n := len(vv)
+		switch {
+		case n < 32:
+			buf = append(buf, 0xa0|byte(n))
+		case n <= math.MaxUint8:
+			buf = append(buf, 0xd9, byte(n))
+		case n <= math.MaxUint16:
+			buf = append(buf, 0xda)
+			buf = appendUint16(buf, uint16(n))
+		default:
+			buf = append(buf, 0xdb)
+			buf = appendUint32(buf, uint32(n))
+		}
+		return append(buf, vv...)
+	case []interface{}:
+		buf = appendMsgPackHeader(buf, len(vv), 0x90, 0xdc, 0xdd)
+		for _, item := range vv {
+			buf = appendMsgPack(buf, item)
+		}
+		return buf
+	case map[string]interface{}:
+		keys := make([]string, 0, len(vv))
+		for k := range vv {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		buf = appendMsgPackHeader(buf, len(vv), 0x80, 0xde, 0xdf)
+		for _, k := range keys {
+			buf = appendMsgPack(buf, k)
+			buf = appendMsgPack(buf, vv[k])
+		}
+		return buf
+	}
+	return appendMsgPack(buf, fmt.Sprintf("%v", v))
+}
+
+// appendMsgPackInt writes an integer in its shortest form
+func appendMsgPackInt(buf []byte, i int64) []byte {
+	switch {
+	case i >= 0 && i <= 127:
+		return append(buf, byte(i))
+	case i >= -32 && i < 0:
+		return append(buf, byte(i))
+	case i >= math.MinInt8 && i <= math.MaxInt8:
+		return append(buf, 0xd0, byte(i))
+	case i >= math.MinInt16 && i <= math.MaxInt16:
+		buf = append(buf, 0xd1)
+		return appendUint16(buf, uint16(i))
+	case i >= math.MinInt32 && i <= math.MaxInt32:
+		buf = append(buf, 0xd2)
+		return appendUint32(buf, uint32(i))
+	}
+	buf = append(buf, 0xd3)
+	return appendUint64(buf, uint64(i))
+}
+
+// appendMsgPackHeader writes the header of an array or a map of n elements
+func appendMsgPackHeader(buf []byte, n int, fix byte, b16 byte, b32 byte) []byte {
+	switch {
+	case n < 16:
+		return append(buf, fix|byte(n))
+	case n <= math.MaxUint16:
+		buf = append(buf, b16)
+		return appendUint16(buf, uint16(n))
+	}
+	buf = append(buf, b32)
+	return appendUint32(buf, uint32(n))
+}
+
+func appendUint16(buf []byte, v uint16) []byte {
+	return append(buf, byte(v>>8), byte(v))
+}
+
+func appendUint32(buf []byte, v uint32) []byte {
+	return append(buf, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
+
+func appendUint64(buf []byte, v uint64) []byte {
+	return appendUint32(appendUint32(buf, uint32(v>>32)), uint32(v))
+}
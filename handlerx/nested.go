@@ -0,0 +1,200 @@
+package handlerx
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// maxNestedIndex limits the list indexes of nested parameters, eg. "items[999].name"
+const maxNestedIndex = 999
+
+// parseNestedKey splits a query parameter name into its path, both dotted and bracketed
+// keys are accepted, eg. "input.network.vlan", "input[network][vlan]" or "items[0].name"
+func parseNestedKey(key string) ([]string, error) {
+	path := make([]string, 0)
+	rest := key
+	for rest != "" {
+		switch {
+		case strings.HasPrefix(rest, "["):
+			end := strings.Index(rest, "]")
+			if end < 0 {
+				return nil, fmt.Errorf("%s: malformed parameter name", key)
+			}
+			path = append(path, rest[1:end])
+			rest = rest[end+1:]
+		case strings.HasPrefix(rest, "."):
+			rest = rest[1:]
+			if rest == "" || strings.HasPrefix(rest, ".") || strings.HasPrefix(rest, "[") {
+				return nil, fmt.Errorf("%s: malformed parameter name", key)
+			}
+		default:
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			path = append(path, rest[:end])
+			rest = rest[end:]
+		}
+	}
+
+	if len(path) == 0 {
+		return nil, fmt.Errorf("%q: malformed parameter name", key)
+	}
+	for _, name := range path {
+		if name == "" {
+			return nil, fmt.Errorf("%s: malformed parameter name", key)
+		}
+	}
+	return path, nil
+}
+
+// parseNestedParams expands the nested query parameters into nested maps and lists. Keys
+// starting with an argument of the operation fill that argument, keys starting with a field
+// of the `input` argument fill the input, other keys are left to the caller. Every level
+// of a matched key is checked against the declared input types, and the key is removed
+// from the query.
+func (m *Mapping) parseNestedParams(argTypes StringMap, query url.Values) (map[string]interface{}, map[string]interface{}, error) {
+	queryParams := make(map[string]interface{})
+	inputParams := make(map[string]interface{})
+
+	inputType := ""
+	var inputFields StringMap
+	if argType, ok := argTypes["input"]; ok {
+		_, inputType = getUnderlayingArgType(argType)
+		inputFields = m.inputTypes[inputType]
+	}
+
+	for key, values := range query {
+		if !strings.ContainsAny(key, ".[") {
+			continue
+		}
+		path, err := parseNestedKey(key)
+		if err != nil {
+			return nil, nil, err
+		}
+		value := strings.Join(values, ",")
+
+		matched := false
+		if path[0] == "input" && inputFields != nil {
+			// input.network.vlan=10
+			if err := m.setNestedParam(inputParams, inputFields, inputType, key, path[1:], value); err != nil {
+				return nil, nil, err
+			}
+			matched = true
+		} else {
+			if _, ok := argTypes[path[0]]; ok {
+				// items[0].name=x
+				if err := m.setNestedParam(queryParams, argTypes, "arguments", key, path, value); err != nil {
+					return nil, nil, err
+				}
+				matched = true
+			}
+			if _, ok := inputFields[path[0]]; ok {
+				// network.vlan=10
+				if err := m.setNestedParam(inputParams, inputFields, inputType, key, path, value); err != nil {
+					return nil, nil, err
+				}
+				matched = true
+			}
+		}
+
+		if matched {
+			query.Del(key)
+		}
+	}
+
+	for k, v := range queryParams {
+		if err := checkNestedParam(v, k); err != nil {
+			return nil, nil, err
+		}
+	}
+	for k, v := range inputParams {
+		if err := checkNestedParam(v, "input."+k); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return queryParams, inputParams, nil
+}
+
+// setNestedParam sets the value at path of an input object with the given fields, checking each level
+func (m *Mapping) setNestedParam(input map[string]interface{}, fields StringMap, typeName string, key string, path []string, value interface{}) error {
+	fieldType, ok := fields[path[0]]
+	if !ok {
+		return fmt.Errorf("%s: unknown field %q of %s", key, path[0], typeName)
+	}
+	isArray, underlayingType := getUnderlayingArgType(fieldType)
+	isInput := m.typeKinds[underlayingType] == "INPUT_OBJECT"
+
+	if len(path) == 1 {
+		if isInput {
+			return fmt.Errorf("%s: field %q of %s requires sub fields", key, path[0], typeName)
+		}
+		input[path[0]] = value
+		return nil
+	}
+
+	if !isArray {
+		if !isInput {
+			return fmt.Errorf("%s: field %q of %s has no sub fields", key, path[0], typeName)
+		}
+		inner, ok := input[path[0]].(map[string]interface{})
+		if !ok {
+			inner = make(map[string]interface{})
+			input[path[0]] = inner
+		}
+		return m.setNestedParam(inner, m.inputTypes[underlayingType], underlayingType, key, path[1:], value)
+	}
+
+	// list elements are addressed by their index, eg. items[0].name
+	index, err := strconv.Atoi(path[1])
+	if err != nil || index < 0 || index > maxNestedIndex {
+		return fmt.Errorf("%s: field %q of %s requires an index between 0 and %d", key, path[0], typeName, maxNestedIndex)
+	}
+	list, _ := input[path[0]].([]interface{})
+	for len(list) <= index {
+		list = append(list, nil)
+	}
+	input[path[0]] = list
+
+	if len(path) == 2 {
+		if isInput {
+			return fmt.Errorf("%s: element of %q requires sub fields", key, path[0])
+		}
+		list[index] = value
+		return nil
+	}
+	if !isInput {
+		return fmt.Errorf("%s: element of %q has no sub fields", key, path[0])
+	}
+	inner, ok := list[index].(map[string]interface{})
+	if !ok {
+		inner = make(map[string]interface{})
+		list[index] = inner
+	}
+	return m.setNestedParam(inner, m.inputTypes[underlayingType], underlayingType, key, path[2:], value)
+}
+
+// checkNestedParam checks that indexed lists have no missing elements
+func checkNestedParam(v interface{}, path string) error {
+	switch vv := v.(type) {
+	case map[string]interface{}:
+		for k, inner := range vv {
+			if err := checkNestedParam(inner, path+"."+k); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for i, inner := range vv {
+			if inner == nil {
+				return fmt.Errorf("%s: missing element %d", path, i)
+			}
+			if err := checkNestedParam(inner, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package handlerx
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseNestedKey(t *testing.T) {
+	tests := []struct {
+		Key         string
+		Expected    []string
+		ShouldError bool
+	}{
+		{Key: "input.network.vlan", Expected: []string{"input", "network", "vlan"}},
+		{Key: "input[network][vlan]", Expected: []string{"input", "network", "vlan"}},
+		{Key: "items[0].name", Expected: []string{"items", "0", "name"}},
+		{Key: "a.b[c].d", Expected: []string{"a", "b", "c", "d"}},
+		{Key: "a..b", ShouldError: true},
+		{Key: "a.", ShouldError: true},
+		{Key: "a[b", ShouldError: true},
+		{Key: "a[]", ShouldError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Key, func(t *testing.T) {
+			path, err := parseNestedKey(tt.Key)
+			if tt.ShouldError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.Expected, path)
+			}
+		})
+	}
+}
+
+func TestNestedParams(t *testing.T) {
+	m := newTestMapping()
+
+	tests := []struct {
+		Name        string
+		Query       string
+		Expected    map[string]interface{}
+		ShouldError bool
+	}{
+		{
+			Name:  "dotted input key",
+			Query: "input.network.vlan=10&input[network][ip]=1.1.1.1",
+			Expected: map[string]interface{}{
+				"id":      "H1",
+				"network": map[string]interface{}{"vlan": json.Number("10"), "ip": "1.1.1.1"},
+			},
+		},
+		{
+			Name:  "input field key",
+			Query: "network.vlan=10",
+			Expected: map[string]interface{}{
+				"id":      "H1",
+				"network": map[string]interface{}{"vlan": json.Number("10")},
+			},
+		},
+		{
+			Name:  "indexed list of input objects",
+			Query: "disks[1].name=b&disks[0].name=a&disks[0].size=20&tags[0]=x",
+			Expected: map[string]interface{}{
+				"id": "H1",
+				"disks": []interface{}{
+					map[string]interface{}{"name": "a", "size": json.Number("20")},
+					map[string]interface{}{"name": "b"},
+				},
+				"tags": []interface{}{"x"},
+			},
+		},
+		{Name: "unknown nested field", Query: "network.mtu=1500", ShouldError: true},
+		{Name: "scalar without sub fields", Query: "network.vlan.id=1", ShouldError: true},
+		{Name: "input object without sub fields", Query: "input.network=1", ShouldError: true},
+		{Name: "list without index", Query: "disks.name=a", ShouldError: true},
+		{Name: "missing list element", Query: "disks[1].name=a", ShouldError: true},
+		{Name: "index out of range", Query: "disks[100000].name=a", ShouldError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			r := newRESTRequest("PUT", "/hosts/H1?"+tt.Query, "/hosts/{id}", "")
+			params := &graphql.RawParams{}
+
+			_, err := m.convertHTTPRequestToGraphQLQuery(context.Background(), r, params, nil)
+			if tt.ShouldError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+
+			assert.Equal(t, tt.Expected, params.Variables["input"])
+		})
+	}
+}
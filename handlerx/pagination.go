@@ -0,0 +1,139 @@
+package handlerx
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Standard paging parameters of paginated list routes
+const (
+	limitParam  = "limit"
+	offsetParam = "offset"
+	afterParam  = "after"
+	beforeParam = "before"
+)
+
+// Pagination is the paging input of a paginated REST list request,
+// eg. "?limit=20&offset=40" or "?limit=20&after=xxx"
+type Pagination struct {
+	Limit  *int64
+	Offset *int64
+	After  string
+	Before string
+}
+
+// PageInfo is the paging metadata of a paginated REST list response
+type PageInfo struct {
+	Limit      *int64 `json:"limit,omitempty"`
+	Offset     *int64 `json:"offset,omitempty"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
+}
+
+func parsePagination(query url.Values) (*Pagination, error) {
+	pagination := &Pagination{
+		After:  query.Get(afterParam),
+		Before: query.Get(beforeParam),
+	}
+
+	for _, param := range []string{limitParam, offsetParam} {
+		v := query.Get(param)
+		if v == "" {
+			continue
+		}
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("%s: expected a non-negative integer, found %q", param, v)
+		}
+		if param == limitParam {
+			pagination.Limit = &n
+		} else {
+			pagination.Offset = &n
+		}
+	}
+
+	if pagination.After != "" && pagination.Before != "" {
+		return nil, fmt.Errorf("%s and %s can not be used together", afterParam, beforeParam)
+	}
+	if pagination.Offset != nil && (pagination.After != "" || pagination.Before != "") {
+		return nil, fmt.Errorf("%s can not be used together with %s or %s", offsetParam, afterParam, beforeParam)
+	}
+
+	return pagination, nil
+}
+
+// pageInfo returns the paging metadata of the response, or nil if the route is not paginated
+func (c *ResponseContext) pageInfo() *PageInfo {
+	if c.pagination == nil {
+		return nil
+	}
+
+	return &PageInfo{
+		Limit:      c.pagination.Limit,
+		Offset:     c.pagination.Offset,
+		NextCursor: c.nextCursor,
+		PrevCursor: c.prevCursor,
+	}
+}
+
+// pageLinks returns the RFC 8288 Link header of the response, eg. `</todos?after=xxx&limit=20>; rel="next"`.
+// Cursor links are used if the resolver reported cursors, otherwise offset links are used if a limit is given.
+func (c *ResponseContext) pageLinks() string {
+	if c.pagination == nil || c.url == nil {
+		return ""
+	}
+
+	links := make([]string, 0)
+	addLink := func(rel string, set map[string]string) {
+		query := c.url.Query()
+		for _, param := range []string{offsetParam, afterParam, beforeParam} {
+			query.Del(param)
+		}
+		for k, v := range set {
+			query.Set(k, v)
+		}
+		u := *c.url
+		u.RawQuery = query.Encode()
+		links = append(links, fmt.Sprintf(`<%s>; rel="%s"`, u.RequestURI(), rel))
+	}
+
+	if c.nextCursor != "" || c.prevCursor != "" {
+		if c.nextCursor != "" {
+			addLink("next", map[string]string{afterParam: c.nextCursor})
+		}
+		if c.prevCursor != "" {
+			addLink("prev", map[string]string{beforeParam: c.prevCursor})
+		}
+		return strings.Join(links, ", ")
+	}
+
+	if c.pagination.Limit == nil || *c.pagination.Limit == 0 {
+		return ""
+	}
+	limit, offset := *c.pagination.Limit, int64(0)
+	if c.pagination.Offset != nil {
+		offset = *c.pagination.Offset
+	}
+	formatOffset := func(n int64) map[string]string {
+		return map[string]string{offsetParam: strconv.FormatInt(n, 10)}
+	}
+
+	addLink("first", formatOffset(0))
+	if offset > 0 {
+		prev := offset - limit
+		if prev < 0 {
+			prev = 0
+		}
+		addLink("prev", formatOffset(prev))
+	}
+	if c.total == nil || offset+limit < *c.total {
+		addLink("next", formatOffset(offset+limit))
+	}
+	if c.total != nil && *c.total > 0 {
+		addLink("last", formatOffset((*c.total-1)/limit*limit))
+	}
+
+	return strings.Join(links, ", ")
+}
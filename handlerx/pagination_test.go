@@ -0,0 +1,90 @@
+package handlerx
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePagination(t *testing.T) {
+	tests := []struct {
+		Name        string
+		Query       string
+		ShouldError bool
+	}{
+		{Name: "offset", Query: "limit=10&offset=20"},
+		{Name: "cursor", Query: "limit=10&after=abc"},
+		{Name: "negative limit", Query: "limit=-1", ShouldError: true},
+		{Name: "invalid offset", Query: "offset=abc", ShouldError: true},
+		{Name: "after and before", Query: "after=a&before=b", ShouldError: true},
+		{Name: "offset and cursor", Query: "offset=1&after=a", ShouldError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			query, _ := url.ParseQuery(tt.Query)
+			_, err := parsePagination(query)
+			if tt.ShouldError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestPaginationLinks(t *testing.T) {
+	h := newTestRouter(NewPreparedQueryCache(nil))
+
+	tests := []struct {
+		Name       string
+		Target     string
+		Link       string
+		Pagination string
+	}{
+		{
+			Name:       "offset",
+			Target:     "/todos?limit=1&offset=0",
+			Link:       `</todos?limit=1&offset=0>; rel="first", </todos?limit=1&offset=1>; rel="next", </todos?limit=1&offset=1>; rel="last"`,
+			Pagination: `{"limit":1,"offset":0}`,
+		},
+		{
+			Name:       "cursor",
+			Target:     "/todos?limit=1&after=T1",
+			Link:       `</todos?after=T2&limit=1>; rel="next", </todos?before=T1&limit=1>; rel="prev"`,
+			Pagination: `{"limit":1,"next_cursor":"T2","prev_cursor":"T1"}`,
+		},
+		{
+			Name:       "without limit",
+			Target:     "/todos",
+			Link:       "",
+			Pagination: `{}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest("GET", tt.Target, nil))
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, tt.Link, w.Header().Get("Link"))
+
+			var response struct {
+				Pagination map[string]interface{} `json:"pagination"`
+			}
+			assert.NoError(t, jsonDecode(w.Body, &response))
+			expected := map[string]interface{}{}
+			assert.NoError(t, jsonDecode(strings.NewReader(tt.Pagination), &expected))
+			assert.Equal(t, expected, response.Pagination)
+		})
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/todos?limit=x", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,126 @@
+package handlerx
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// ErrorFormat is the format of the error responses of REST requests
+type ErrorFormat int
+
+const (
+	// EnvelopeErrorFormat writes errors as RESTResponse, with code, codestr and message
+	EnvelopeErrorFormat ErrorFormat = iota
+	// ProblemErrorFormat writes errors as RFC 7807 problem documents, see ProblemDetails
+	ProblemErrorFormat
+)
+
+const problemContentType = "application/problem+json"
+
+// ProblemDetails is an RFC 7807 problem document, see https://www.rfc-editor.org/rfc/rfc7807
+type ProblemDetails struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	// extension members
+	CodeStr   string          `json:"codestr,omitempty"`
+	Errors    []*ProblemError `json:"errors,omitempty"`
+	RequestID string          `json:"request_id,omitempty"`
+}
+
+// ProblemError is one GraphQL error of a problem document
+type ProblemError struct {
+	Message string   `json:"message"`
+	Path    ast.Path `json:"path,omitempty"`
+	Code    string   `json:"code,omitempty"`
+}
+
+// SetErrorFormat sets the format of the error responses of the REST routes
+func (m *Mapping) SetErrorFormat(format ErrorFormat) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.errorFormat = format
+}
+
+// WithErrorFormat sets the format of the error responses when the mapping is created, the generated
+// RegisterHandlers sets the one rest.yaml is generated with before its options
+func WithErrorFormat(format ErrorFormat) MappingOption {
+	return func(m *Mapping) {
+		m.SetErrorFormat(format)
+	}
+}
+
+// errorFormatFor returns the error format of the mapping carried by the context
+func errorFormatFor(ctx context.Context) ErrorFormat {
+	if m := GetMapping(ctx); m != nil {
+		m.mu.RLock()
+		defer m.mu.RUnlock()
+
+		return m.errorFormat
+	}
+	return EnvelopeErrorFormat
+}
+
+// writeErrorHeader writes the HTTP status of an error response. RESTful errors get their content
+// type, and in the problem format the status is kept for the problem document.
+func writeErrorHeader(ctx context.Context, w http.ResponseWriter, isRESTful bool, status int) {
+	responseCtx := GetResponseContext(ctx)
+	if isRESTful && errorFormatFor(ctx) == ProblemErrorFormat {
+		w.Header().Set("Content-Type", problemContentType)
+		if responseCtx != nil {
+			responseCtx.errorStatus = status
+		}
+	} else if isRESTful && responseCtx != nil && responseCtx.mediaType != "" {
+		w.Header().Set("Content-Type", responseCtx.mediaType)
+	}
+	w.WriteHeader(status)
+}
+
+// writeProblem writes the errors of a RESTful response as a problem document
+func writeProblem(ctx context.Context, w http.ResponseWriter, errs gqlerror.List, status int, codeStr string, detail string) {
+	problem := &ProblemDetails{
+		Type:    "about:blank",
+		Status:  status,
+		Detail:  detail,
+		CodeStr: codeStr,
+	}
+
+	responseCtx := GetResponseContext(ctx)
+	if responseCtx != nil && responseCtx.errorStatus != 0 {
+		// the status is already written
+		problem.Status = responseCtx.errorStatus
+	} else {
+		w.Header().Set("Content-Type", problemContentType)
+		w.WriteHeader(status)
+	}
+	if responseCtx != nil && responseCtx.url != nil {
+		problem.Instance = responseCtx.url.Path
+	}
+	if responseCtx != nil {
+		problem.RequestID = responseCtx.requestID
+	}
+	problem.Title = http.StatusText(problem.Status)
+
+	for _, e := range errs {
+		problem.Errors = append(problem.Errors, &ProblemError{
+			Message: e.Message,
+			Path:    e.Path,
+			Code:    errorCode(e),
+		})
+	}
+
+	b, err := json.Marshal(problem)
+	if err != nil {
+		panic(err)
+	}
+	if _, err := w.Write(b); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,89 @@
+package handlerx
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProblemErrorFormat(t *testing.T) {
+	m := newTestMapping()
+	m.SetErrorFormat(ProblemErrorFormat)
+	m.SetErrorMapper(ErrorCodeMapper{"NOT_FOUND": http.StatusNotFound})
+	h := newTestMappingRouter(m, NewPreparedQueryCache(nil))
+
+	tests := []struct {
+		Name           string
+		Method         string
+		Target         string
+		Body           string
+		ExpectedStatus int
+		Expected       string
+	}{
+		{
+			Name:           "resolver error",
+			Method:         "GET",
+			Target:         "/todos/missing",
+			ExpectedStatus: http.StatusNotFound,
+			Expected: `{"type":"about:blank","title":"Not Found","status":404,"detail":"todo not found",
+				"instance":"/todos/missing","errors":[{"message":"todo not found","code":"NOT_FOUND"}],"request_id":"test-request-id"}`,
+		},
+		{
+			Name:           "mapping error",
+			Method:         "GET",
+			Target:         "/todos?fields=unknown",
+			ExpectedStatus: http.StatusBadRequest,
+			Expected: `{"type":"about:blank","title":"Bad Request","status":400,"detail":"fields: unknown field \"unknown\"",
+				"instance":"/todos","errors":[{"message":"fields: unknown field \"unknown\"","code":"400"}],"request_id":"test-request-id"}`,
+		},
+		{
+			Name:           "invalid parameter",
+			Method:         "GET",
+			Target:         "/todos?done=maybe",
+			ExpectedStatus: http.StatusBadRequest,
+			Expected: `{"type":"about:blank","title":"Bad Request","status":400,"detail":"query parameter done: expected Boolean, found \"maybe\"",
+				"instance":"/todos","errors":[{"message":"query parameter done: expected Boolean, found \"maybe\"","code":"400"}],"request_id":"test-request-id"}`,
+		},
+		{
+			Name:           "validation error",
+			Method:         "POST",
+			Target:         "/todos",
+			Body:           `{"input":{"text":"buy milk"}}`,
+			ExpectedStatus: http.StatusUnprocessableEntity,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(tt.Method, tt.Target, strings.NewReader(tt.Body))
+			r.Header.Set("Content-Type", "application/json")
+			h.ServeHTTP(w, r)
+
+			assert.Equal(t, tt.ExpectedStatus, w.Code)
+			assert.Equal(t, "application/problem+json", w.Header().Get("Content-Type"))
+			if tt.Expected != "" {
+				assert.JSONEq(t, tt.Expected, w.Body.String())
+			}
+		})
+	}
+
+	t.Run("successful response", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", "/todos/T1", nil))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+		assert.JSONEq(t, `{"code":0,"data":{"done":false,"id":"T1","text":"buy milk"}}`, w.Body.String())
+	})
+}
+
+func TestWithErrorFormat(t *testing.T) {
+	assert.Equal(t, EnvelopeErrorFormat, NewMapping().errorFormat)
+	assert.Equal(t, ProblemErrorFormat, NewMapping(WithErrorFormat(ProblemErrorFormat)).errorFormat)
+	// the options of RegisterHandlers are applied after the generated error format
+	assert.Equal(t, EnvelopeErrorFormat, NewMapping(WithErrorFormat(ProblemErrorFormat), WithErrorFormat(EnvelopeErrorFormat)).errorFormat)
+}
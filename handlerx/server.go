@@ -9,23 +9,69 @@ import (
 
 	"github.com/99designs/gqlgen/graphql/handler"
 	"github.com/99designs/gqlgen/graphql/handler/lru"
+	"github.com/speedoops/go-gqlrest/constraint"
 )
 
-func NewDefaultServer(es graphql.ExecutableSchema) *handler.Server {
+// ServerOption configures the server of NewDefaultServer
+type ServerOption func(*serverOptions)
+
+type serverOptions struct {
+	tracer      *Tracer
+	constraints bool
+	formats     constraint.Formats
+}
+
+// WithTracer traces the requests of the GET, POST, DELETE, SSE, NDJSON and batch transports, eg. with
+// the spans kept in memory for the tests:
+//
+//	exporter := tracetest.NewInMemoryExporter()
+//	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+//	srv := handlerx.NewDefaultServer(es, handlerx.WithTracer(handlerx.NewTracer(provider)))
+func WithTracer(tracer *Tracer) ServerOption {
+	return func(o *serverOptions) {
+		o.tracer = tracer
+	}
+}
+
+// WithConstraintFormats installs the ConstraintValidator, with the formats of the @constraintString
+// directives, ie. the ConstraintFormats of the generated code:
+//
+//	srv := handlerx.NewDefaultServer(es, handlerx.WithConstraintFormats(generated.ConstraintFormats))
+func WithConstraintFormats(formats constraint.Formats) ServerOption {
+	return func(o *serverOptions) {
+		o.constraints = true
+		o.formats = formats
+	}
+}
+
+func NewDefaultServer(es graphql.ExecutableSchema, opts ...ServerOption) *handler.Server {
+	o := &serverOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
 	srv := handler.New(es)
 
 	srv.AddTransport(transport.Websocket{
 		KeepAlivePingInterval: 10 * time.Second,
 	})
 	srv.AddTransport(Options{})
-	srv.AddTransport(GET{})
-	srv.AddTransport(POST{})
-	srv.AddTransport(DELETE{})
+	srv.AddTransport(SSE{
+		KeepAlivePingInterval: 10 * time.Second,
+		Tracer:                o.tracer,
+	})
+	srv.AddTransport(NDJSON{Tracer: o.tracer})
+	srv.AddTransport(GET{Tracer: o.tracer})
+	srv.AddTransport(Batch{Concurrency: 4, Tracer: o.tracer})
+	srv.AddTransport(POST{Tracer: o.tracer})
+	srv.AddTransport(DELETE{Tracer: o.tracer})
 	srv.AddTransport(transport.MultipartForm{})
 
-	srv.SetQueryCache(lru.New(1000))
+	srv.SetQueryCache(NewPreparedQueryCache(lru.New(1000)))
 
 	srv.Use(extension.Introspection{})
+	if o.constraints {
+		srv.Use(&ConstraintValidator{Formats: o.formats})
+	}
 	srv.Use(extension.AutomaticPersistedQuery{
 		Cache: lru.New(100),
 	})
@@ -0,0 +1,375 @@
+package handlerx
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/lru"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vektah/gqlparser/v2"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+var testSchema = gqlparser.MustLoadSchema(&ast.Source{Input: `
+	enum TodoState { TODO DONE }
+	type Todo {
+		id: ID!
+		text: String!
+		done: Boolean!
+	}
+	input NewTodoInput {
+		text: String!
+		userId: ID!
+		priority: Int
+	}
+	enum SortOrder { ASC DESC }
+	type Host {
+		id: ID!
+		name: String!
+		status: String!
+	}
+	input HostFilter {
+		name: String
+		status: StringFilter
+	}
+	input StringFilter {
+		eq: String
+		in: [String!]
+	}
+	input HostSort {
+		name: SortOrder
+		created: SortOrder
+	}
+	input UpdateHostInput {
+		id: ID!
+		network: NetworkInput
+		disks: [DiskInput!]
+		tags: [String!]
+	}
+	input NetworkInput {
+		vlan: Int
+		ip: String
+	}
+	input DiskInput {
+		name: String!
+		size: Int
+	}
+	type Query {
+		hosts(filter: HostFilter, sort: [HostSort!]): [Host!]!
+		todos(ids: [ID!], limit: Int, done: Boolean, state: TodoState): [Todo!]!
+		todo(id: ID!): Todo
+	}
+	type Mutation {
+		createTodo(input: NewTodoInput!): Todo!
+		deleteTodo(id: ID!): Boolean!
+		updateHost(input: UpdateHostInput!): Host!
+	}
+	type Subscription {
+		todoAdded: Todo!
+	}
+`})
+
+// newTestExecutableSchema resolves the root field of the test schema with canned data,
+// it is good enough to exercise the transports without generated code.
+func newTestExecutableSchema() graphql.ExecutableSchema {
+	return &graphql.ExecutableSchemaMock{
+		ExecFunc: func(ctx context.Context) graphql.ResponseHandler {
+			rc := graphql.GetOperationContext(ctx)
+			field := rc.Operation.SelectionSet[0].(*ast.Field)
+			args := field.ArgumentMap(rc.Variables)
+
+			if rc.Operation.Operation == ast.Subscription {
+				return newTestSubscription(ctx, field)
+			}
+
+			var data interface{}
+			switch field.Name {
+			case "todos":
+				data = []map[string]interface{}{
+					{"id": "T1", "text": "buy milk", "done": false},
+					{"id": "T2", "text": "walk dog", "done": true},
+				}
+				if responseCtx := GetResponseContext(ctx); responseCtx != nil {
+					responseCtx.SetTotal(2)
+					if pagination := responseCtx.Pagination(); pagination != nil && pagination.After != "" {
+						responseCtx.SetNextCursor("T2")
+						responseCtx.SetPrevCursor("T1")
+					}
+				}
+			case "todo":
+				if args["id"] == "missing" {
+					return graphql.OneShot(&graphql.Response{
+						Errors: gqlerror.List{{Message: "todo not found", Extensions: map[string]interface{}{"code": "NOT_FOUND"}}},
+						Data:   []byte(`{"todo":null}`),
+					})
+				}
+				data = map[string]interface{}{"id": args["id"], "text": "buy milk", "done": false}
+			case "createTodo":
+				input := args["input"].(map[string]interface{})
+				data = map[string]interface{}{"id": "T3", "text": input["text"], "done": false}
+			case "deleteTodo":
+				data = true
+			}
+
+			b, _ := json.Marshal(map[string]interface{}{field.Name: projectTestData(data, field.SelectionSet)})
+			return graphql.OneShot(&graphql.Response{Data: b})
+		},
+		SchemaFunc: func() *ast.Schema {
+			return testSchema
+		},
+		ComplexityFunc: func(typeName, fieldName string, childComplexity int, args map[string]interface{}) (int, bool) {
+			return 0, false
+		},
+	}
+}
+
+// newTestSubscription sends the todos after the Last-Event-ID of the request, one per payload
+func newTestSubscription(ctx context.Context, field *ast.Field) graphql.ResponseHandler {
+	todos := []map[string]interface{}{
+		{"id": "T1", "text": "buy milk", "done": false},
+		{"id": "T2", "text": "walk dog", "done": true},
+		{"id": "T3", "text": "feed cat", "done": false},
+	}
+	next := 0
+	if responseCtx := GetResponseContext(ctx); responseCtx != nil && responseCtx.LastEventID() != "" {
+		fmt.Sscan(responseCtx.LastEventID(), &next)
+	}
+
+	return func(ctx context.Context) *graphql.Response {
+		if next >= len(todos) {
+			return nil
+		}
+		b, _ := json.Marshal(map[string]interface{}{field.Name: projectTestData(todos[next], field.SelectionSet)})
+		next++
+		return &graphql.Response{Data: b}
+	}
+}
+
+// projectTestData keeps only the selected fields of the canned data
+func projectTestData(data interface{}, selectionSet ast.SelectionSet) interface{} {
+	switch v := data.(type) {
+	case []map[string]interface{}:
+		ret := make([]interface{}, 0, len(v))
+		for _, item := range v {
+			ret = append(ret, projectTestData(item, selectionSet))
+		}
+		return ret
+	case map[string]interface{}:
+		ret := make(map[string]interface{})
+		for _, selection := range selectionSet {
+			if f, ok := selection.(*ast.Field); ok {
+				ret[f.Alias] = projectTestData(v[f.Name], f.SelectionSet)
+			}
+		}
+		return ret
+	}
+	return data
+}
+
+func TestMain(m *testing.M) {
+	// the error envelopes carry the request ID
+	RegisterRequestIDGenerator(func() string { return "test-request-id" })
+	os.Exit(m.Run())
+}
+
+func newTestRouter(cache graphql.Cache) http.Handler {
+	return newTestMappingRouter(newTestMapping(), cache)
+}
+
+func newTestMappingRouter(m *Mapping, cache graphql.Cache) http.Handler {
+	if err := m.Prepare(testSchema); err != nil {
+		panic(err)
+	}
+
+	srv := handler.New(newTestExecutableSchema())
+	srv.AddTransport(Options{})
+	srv.AddTransport(SSE{})
+	srv.AddTransport(NDJSON{})
+	srv.AddTransport(GET{})
+	srv.AddTransport(Batch{Concurrency: 2})
+	srv.AddTransport(POST{})
+	srv.AddTransport(DELETE{})
+	srv.SetQueryCache(cache)
+
+	r := chi.NewRouter()
+	m.Bind(ChiBinder{Router: r}, srv)
+	m.BindBatch(ChiBinder{Router: r}, srv, "/batch")
+	return r
+}
+
+func TestPrepareMapping(t *testing.T) {
+	m := newTestMapping()
+	require.NoError(t, m.Prepare(testSchema))
+	assert.Len(t, m.documents, 6)
+
+	m.selections["todo"] = "{id,unknown}"
+	m.Setup(m.operations, m.selections, m.arguments, m.inputTypes, m.typeKinds)
+	err := m.Prepare(testSchema)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "GET:/todos/{id}")
+	assert.Contains(t, err.Error(), "unknown")
+	assert.Nil(t, m.documents)
+}
+
+func TestMappingsOnOneServer(t *testing.T) {
+	public := NewMapping()
+	public.Setup(
+		StringMap{"GET:/public/todos/{id}": "todo"},
+		StringMap{"todo": "{id,text}"},
+		ArgTypeMap{"todo": {"id": "ID!"}},
+		ArgTypeMap{},
+		StringMap{},
+	)
+	internal := NewMapping()
+	internal.Setup(
+		StringMap{"GET:/internal/todos/{id}": "todo"},
+		StringMap{"todo": "{id,text,done}"},
+		ArgTypeMap{"todo": {"id": "ID!"}},
+		ArgTypeMap{},
+		StringMap{},
+	)
+
+	srv := handler.New(newTestExecutableSchema())
+	srv.AddTransport(GET{})
+	srv.SetQueryCache(NewPreparedQueryCache(nil))
+
+	r := chi.NewRouter()
+	for _, m := range []*Mapping{public, internal} {
+		require.NoError(t, m.Prepare(testSchema))
+		m.Bind(ChiBinder{Router: r}, srv)
+	}
+
+	tests := []struct {
+		Name     string
+		Target   string
+		Expected string
+	}{
+		{"public", "/public/todos/T1", `{"code":0,"data":{"id":"T1","text":"buy milk"}}`},
+		{"internal", "/internal/todos/T1", `{"code":0,"data":{"done":false,"id":"T1","text":"buy milk"}}`},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.Name, func(t *testing.T) {
+			t.Parallel()
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest("GET", tt.Target, nil))
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.JSONEq(t, tt.Expected, w.Body.String())
+		})
+	}
+
+	t.Run("transport mapping", func(t *testing.T) {
+		srv := handler.New(newTestExecutableSchema())
+		srv.AddTransport(GET{Mapping: internal})
+
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, newRESTRequest("GET", "/internal/todos/T2", "/internal/todos/{id}", ""))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"code":0,"data":{"done":false,"id":"T2","text":"buy milk"}}`, w.Body.String())
+	})
+}
+
+func TestRESTTransports(t *testing.T) {
+	h := newTestRouter(NewPreparedQueryCache(nil))
+
+	tests := []struct {
+		Name     string
+		Method   string
+		Target   string
+		Body     string
+		Expected string
+	}{
+		{
+			Name:     "GET list",
+			Method:   "GET",
+			Target:   "/todos?limit=10&done=true",
+			Expected: `{"code":0,"data":[{"done":false,"id":"T1","text":"buy milk"},{"done":true,"id":"T2","text":"walk dog"}],"total":2,"pagination":{"limit":10}}`,
+		},
+		{
+			Name:     "GET path parameter",
+			Method:   "GET",
+			Target:   "/todos/T9",
+			Expected: `{"code":0,"data":{"done":false,"id":"T9","text":"buy milk"}}`,
+		},
+		{
+			Name:     "POST input",
+			Method:   "POST",
+			Target:   "/todos",
+			Body:     `{"input":{"text":"buy milk","userId":"U1"}}`,
+			Expected: `{"code":0,"data":{"done":false,"id":"T3","text":"buy milk"}}`,
+		},
+		{
+			Name:     "DELETE",
+			Method:   "DELETE",
+			Target:   "/todos/T1",
+			Expected: `{"code":0,"data":true}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(tt.Method, tt.Target, strings.NewReader(tt.Body))
+			r.Header.Set("Content-Type", "application/json")
+			h.ServeHTTP(w, r)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.JSONEq(t, tt.Expected, w.Body.String())
+		})
+	}
+}
+
+func benchmarkTransport(b *testing.B, method string, target string, body string) {
+	caches := []struct {
+		Name  string
+		Cache func() graphql.Cache
+	}{
+		{"parse", func() graphql.Cache { return graphql.NoCache{} }},
+		{"lru", func() graphql.Cache { return lru.New(1000) }},
+		{"prepared", func() graphql.Cache { return NewPreparedQueryCache(lru.New(1000)) }},
+	}
+
+	for _, c := range caches {
+		b.Run(c.Name, func(b *testing.B) {
+			h := newTestRouter(c.Cache())
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				w := httptest.NewRecorder()
+				r := httptest.NewRequest(method, target, strings.NewReader(body))
+				r.Header.Set("Content-Type", "application/json")
+				h.ServeHTTP(w, r)
+				if w.Code != http.StatusOK {
+					panic(fmt.Sprintf("unexpected status %d: %s", w.Code, w.Body.String()))
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkGET(b *testing.B) {
+	benchmarkTransport(b, "GET", "/todos?ids=T1,T2&limit=10&done=true&state=DONE", "")
+}
+
+func BenchmarkPOST(b *testing.B) {
+	benchmarkTransport(b, "POST", "/todos", `{"input":{"text":"buy milk","userId":"U1","priority":1}}`)
+}
+
+func BenchmarkDELETE(b *testing.B) {
+	benchmarkTransport(b, "DELETE", "/todos/T1", "")
+}
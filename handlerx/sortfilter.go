@@ -0,0 +1,210 @@
+package handlerx
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// Standard sorting and filtering parameters of list routes, eg.
+// "?sort=-created,name&filter[name]=foo&filter[status][in]=a,b"
+const (
+	sortParam   = "sort"
+	filterParam = "filter"
+
+	sortAscending  = "ASC"
+	sortDescending = "DESC"
+	// sortDescendingSuffix is the suffix of the descending values of an enum sort, eg. "CREATED_DESC"
+	sortDescendingSuffix = "_DESC"
+)
+
+// parseFilterParams collects the "filter[...]" query parameters into the value of the filter
+// argument. Every key is checked against the fields of the declared input type, and removed
+// from the query.
+func (m *Mapping) parseFilterParams(argTypes StringMap, query url.Values) (map[string]interface{}, error) {
+	var filter map[string]interface{}
+	for key, values := range query {
+		path, err := parseNestedKey(key)
+		if err != nil {
+			return nil, err
+		}
+		if path[0] != filterParam || len(path) == 1 {
+			continue
+		}
+
+		argType, ok := argTypes[filterParam]
+		if !ok {
+			return nil, fmt.Errorf("%s: filtering is not supported", key)
+		}
+		_, filterType := getUnderlayingArgType(argType)
+		fields, ok := m.inputTypes[filterType]
+		if !ok {
+			return nil, fmt.Errorf("%s: %s is not an input object", key, filterType)
+		}
+		if filter == nil {
+			filter = make(map[string]interface{})
+		}
+		if err := m.setNestedParam(filter, fields, filterType, key, path[1:], strings.Join(values, ",")); err != nil {
+			return nil, err
+		}
+		query.Del(key)
+	}
+	if err := checkNestedParam(filter, filterParam); err != nil {
+		return nil, err
+	}
+	return filter, nil
+}
+
+// parseSortParam converts "?sort=-created,name" into the value of the sort argument.
+// An input object, or a list of input objects, gets the keys as its fields, with the ascending or
+// descending value of their direction enum as their values. An enum, or a list of enums, gets the keys
+// as its values, matched case insensitively, "-created" being the value "CREATED_DESC" of the enum.
+// A list of scalars gets the keys as they are, left to the resolver.
+func (m *Mapping) parseSortParam(argType string, value string) (interface{}, error) {
+	isArray, underlayingType := getUnderlayingArgType(argType)
+	if m.typeKinds[underlayingType] == "ENUM" {
+		return m.parseEnumSortParam(isArray, underlayingType, value)
+	}
+	if m.typeKinds[underlayingType] != "INPUT_OBJECT" {
+		return value, nil
+	}
+
+	fields := m.inputTypes[underlayingType]
+	sorts := make([]interface{}, 0)
+	merged := make(map[string]interface{})
+	for _, key := range strings.Split(value, ",") {
+		key, descending := parseSortKey(key)
+		if key == "" {
+			continue
+		}
+		fieldType, ok := fields[key]
+		if !ok {
+			return nil, fmt.Errorf("%s: unknown sort key %q", sortParam, key)
+		}
+		_, directionType := getUnderlayingArgType(fieldType)
+		ascending, descendingValue, err := m.sortDirections(directionType)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", sortParam, err.Error())
+		}
+		order := ascending
+		if descending {
+			order = descendingValue
+		}
+		sorts = append(sorts, map[string]interface{}{key: order})
+		merged[key] = order
+	}
+
+	if isArray {
+		return sorts, nil
+	}
+	return merged, nil
+}
+
+// parseEnumSortParam converts the keys of the sort parameter into the values of the enum
+func (m *Mapping) parseEnumSortParam(isArray bool, enumType string, value string) (interface{}, error) {
+	values, prepared := m.enumValues[enumType]
+	sorts := make([]interface{}, 0)
+	for _, key := range strings.Split(value, ",") {
+		key, descending := parseSortKey(key)
+		if key == "" {
+			continue
+		}
+		name := key
+		if descending {
+			name += sortDescendingSuffix
+		}
+		if !prepared {
+			// the values are only known once the mapping is prepared, they are checked by the GraphQL validation
+			if descending {
+				return nil, fmt.Errorf("%s: descending sort by %q is not supported", sortParam, key)
+			}
+			sorts = append(sorts, key)
+			continue
+		}
+
+		enumValue := ""
+		for _, v := range values {
+			if strings.EqualFold(v, name) {
+				enumValue = v
+				break
+			}
+		}
+		if enumValue == "" && descending && containsFold(values, key) {
+			return nil, fmt.Errorf("%s: descending sort by %q is not supported", sortParam, key)
+		}
+		if enumValue == "" {
+			return nil, fmt.Errorf("%s: unknown sort key %q", sortParam, key)
+		}
+		sorts = append(sorts, enumValue)
+	}
+
+	if isArray {
+		return sorts, nil
+	}
+	if len(sorts) > 1 {
+		return nil, fmt.Errorf("%s: only one sort key is allowed", sortParam)
+	}
+	if len(sorts) == 0 {
+		return nil, nil
+	}
+	return sorts[0], nil
+}
+
+// parseSortKey parses one key of the sort parameter, "-created" sorts by created in descending order
+func parseSortKey(key string) (string, bool) {
+	key = strings.TrimSpace(key)
+	if strings.HasPrefix(key, "-") {
+		return key[1:], true
+	}
+	return strings.TrimPrefix(key, "+"), false
+}
+
+// sortDirections returns the ascending and descending values of the direction enum of an input
+// object sort, ie. "ASC" or "ASCENDING" and "DESC" or "DESCENDING", matched case insensitively.
+// The values are only known once the mapping is prepared, "ASC" and "DESC" are assumed before.
+func (m *Mapping) sortDirections(enumType string) (string, string, error) {
+	values, ok := m.enumValues[enumType]
+	if !ok {
+		return sortAscending, sortDescending, nil
+	}
+
+	ascending, descending := "", ""
+	for _, v := range values {
+		switch strings.ToUpper(v) {
+		case "ASC", "ASCENDING":
+			ascending = v
+		case "DESC", "DESCENDING":
+			descending = v
+		}
+	}
+	if ascending == "" || descending == "" {
+		return "", "", fmt.Errorf("sort direction %s has no ascending or descending value", enumType)
+	}
+	return ascending, descending, nil
+}
+
+// checkSortArgument checks the direction enums of the sort argument of an operation, if any
+func (m *Mapping) checkSortArgument(argTypes StringMap) error {
+	argType, ok := argTypes[sortParam]
+	if !ok {
+		return nil
+	}
+	_, underlayingType := getUnderlayingArgType(argType)
+	if m.typeKinds[underlayingType] != "INPUT_OBJECT" {
+		return nil
+	}
+
+	keys := make([]string, 0)
+	for key := range m.inputTypes[underlayingType] {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		_, directionType := getUnderlayingArgType(m.inputTypes[underlayingType][key])
+		if _, _, err := m.sortDirections(directionType); err != nil {
+			return fmt.Errorf("%s: %s", sortParam, err.Error())
+		}
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+package handlerx
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/stretchr/testify/assert"
+	"github.com/vektah/gqlparser/v2"
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+func TestSortAndFilterParams(t *testing.T) {
+	m := newTestMapping()
+
+	tests := []struct {
+		Name        string
+		Query       string
+		Expected    map[string]interface{}
+		ShouldError bool
+	}{
+		{
+			Name:  "filter",
+			Query: "filter[name]=foo&filter[status][in]=a,b",
+			Expected: map[string]interface{}{
+				"filter": map[string]interface{}{
+					"name":   "foo",
+					"status": map[string]interface{}{"in": []interface{}{"a", "b"}},
+				},
+			},
+		},
+		{
+			Name:  "sort",
+			Query: "sort=-created,name",
+			Expected: map[string]interface{}{
+				"sort": []interface{}{
+					map[string]interface{}{"created": "DESC"},
+					map[string]interface{}{"name": "ASC"},
+				},
+			},
+		},
+		{Name: "unknown filter field", Query: "filter[owner]=foo", ShouldError: true},
+		{Name: "unknown filter operator", Query: "filter[status][like]=a", ShouldError: true},
+		{Name: "filter input without operator", Query: "filter[status]=a", ShouldError: true},
+		{Name: "malformed filter", Query: "filter[name=foo", ShouldError: true},
+		{Name: "unknown sort key", Query: "sort=owner", ShouldError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			r := newRESTRequest("GET", "/hosts?"+tt.Query, "/hosts", "")
+			params := &graphql.RawParams{}
+
+			_, err := m.convertHTTPRequestToGraphQLQuery(context.Background(), r, params, nil)
+			if tt.ShouldError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.Expected, params.Variables)
+			}
+		})
+	}
+
+	t.Run("filter on route without filter argument", func(t *testing.T) {
+		h := newTestRouter(NewPreparedQueryCache(nil))
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", "/todos?filter[name]=foo", nil))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+var testSortSchema = gqlparser.MustLoadSchema(&ast.Source{Input: `
+	enum Direction { ASCENDING DESCENDING }
+	enum LabelSort { CREATED CREATED_DESC NAME }
+	input GroupSort {
+		name: Direction
+	}
+	type Query {
+		labels(sort: [LabelSort!]): [String!]!
+		label(sort: LabelSort): String
+		groups(sort: GroupSort): [String!]!
+	}
+`})
+
+func newTestSortMapping() *Mapping {
+	m := NewMapping()
+	m.Setup(
+		StringMap{"GET:/labels": "labels", "GET:/label": "label", "GET:/groups": "groups"},
+		StringMap{"labels": "", "label": "", "groups": ""},
+		ArgTypeMap{
+			"labels": {"sort": "[LabelSort!]"},
+			"label":  {"sort": "LabelSort"},
+			"groups": {"sort": "GroupSort"},
+		},
+		ArgTypeMap{"GroupSort": {"name": "Direction"}},
+		StringMap{"Direction": "ENUM", "LabelSort": "ENUM", "GroupSort": "INPUT_OBJECT"},
+	)
+	return m
+}
+
+func TestEnumSortParam(t *testing.T) {
+	m := newTestSortMapping()
+	if err := m.Prepare(testSortSchema); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		Name        string
+		Route       string
+		Query       string
+		Expected    interface{}
+		ShouldError bool
+	}{
+		{Name: "enum list", Route: "/labels", Query: "sort=-created,name", Expected: []interface{}{"CREATED_DESC", "NAME"}},
+		{Name: "enum", Route: "/label", Query: "sort=created", Expected: "CREATED"},
+		{Name: "direction enum of the schema", Route: "/groups", Query: "sort=-name", Expected: map[string]interface{}{"name": "DESCENDING"}},
+		{Name: "descending sort not supported", Route: "/labels", Query: "sort=-name", ShouldError: true},
+		{Name: "unknown enum sort key", Route: "/labels", Query: "sort=owner", ShouldError: true},
+		{Name: "several keys for one enum", Route: "/label", Query: "sort=created,name", ShouldError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			r := newRESTRequest("GET", tt.Route+"?"+tt.Query, tt.Route, "")
+			params := &graphql.RawParams{}
+
+			_, err := m.convertHTTPRequestToGraphQLQuery(context.Background(), r, params, nil)
+			if tt.ShouldError {
+				var mappingErr *mappingError
+				if assert.ErrorAs(t, err, &mappingErr) {
+					assert.Equal(t, http.StatusBadRequest, mappingErr.code)
+				}
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.Expected, params.Variables["sort"])
+			}
+		})
+	}
+}
+
+func TestSortDirections(t *testing.T) {
+	m := newTestSortMapping()
+	err := m.Prepare(gqlparser.MustLoadSchema(&ast.Source{Input: `
+		enum Direction { UP DOWN }
+		enum LabelSort { CREATED }
+		input GroupSort {
+			name: Direction
+		}
+		type Query {
+			labels(sort: [LabelSort!]): [String!]!
+			label(sort: LabelSort): String
+			groups(sort: GroupSort): [String!]!
+		}
+	`}))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "GET:/groups: sort: sort direction Direction has no ascending or descending value")
+}
@@ -0,0 +1,101 @@
+package handlerx
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/99designs/gqlgen/graphql"
+)
+
+// ndjsonTrailer is the last line of a streamed response, with everything but the data
+type ndjsonTrailer struct {
+	Code    int    `json:"code"`
+	CodeStr string `json:"codestr,omitempty"`
+	Message string `json:"message,omitempty"`
+	Total   *int64 `json:"total,omitempty"`
+}
+
+// NDJSON serves the subscription routes with the Stream option as newline delimited JSON, see
+// https://github.com/ndjson/ndjson-spec. Every payload of the subscription is written and flushed
+// as one line as soon as the resolver sends it, and a trailing line carries the code, errors and total.
+// The resolver sends the elements of the list one by one and closes the channel at the end.
+// Query lists are resolved and marshaled as a whole by the executor, so they can not be streamed.
+type NDJSON struct {
+	Mapping *Mapping
+	Tracer  *Tracer
+}
+
+var _ graphql.Transport = NDJSON{}
+
+func (h NDJSON) Supports(r *http.Request) bool {
+	if r.Method != http.MethodGet {
+		return false
+	}
+	mapping := requestMapping(h.Mapping, r)
+	if mapping == nil {
+		return false
+	}
+	pattern, _, _ := requestRoute(r)
+	return mapping.streamMediaType(http.MethodGet+":"+pattern) == MediaTypeNDJSON
+}
+
+func (h NDJSON) Do(w http.ResponseWriter, r *http.Request, exec graphql.GraphExecutor) {
+	serveSubscription(w, r, exec, requestMapping(h.Mapping, r), h.Tracer, MediaTypeNDJSON,
+		func(ctx context.Context, w http.ResponseWriter, flusher http.Flusher, responses graphql.ResponseHandler) {
+			if err := writeNDJSON(ctx, w, flusher, responses); err != nil {
+				dbgPrintf("HTTP %s %s: stream could not be written: %v", r.Method, r.URL.Path, err)
+			}
+		})
+}
+
+// writeNDJSON writes the payloads of a subscription one line each, until the subscription ends or
+// fails, and then the trailer. The total of the trailer is the one set by the resolver, if any,
+// or else the number of lines written.
+func writeNDJSON(ctx context.Context, w http.ResponseWriter, flusher http.Flusher, responses graphql.ResponseHandler) error {
+	writeLine := func(line []byte) error {
+		if _, err := w.Write(line); err != nil {
+			return err
+		}
+		if _, err := w.Write([]byte{'\n'}); err != nil {
+			return err
+		}
+		flusher.Flush()
+		return nil
+	}
+
+	trailer := &ndjsonTrailer{}
+	var lines int64
+	for {
+		response := responses(ctx)
+		if response == nil {
+			break
+		}
+		if len(response.Errors) > 0 {
+			trailer.Code, trailer.CodeStr, trailer.Message = parseErrCodeFromGqlErrors(response.Errors, errorMapperFor(ctx))
+			break
+		}
+		data, err := unwrapData(response.Data)
+		if err != nil {
+			return err
+		}
+		if err := writeLine(data); err != nil {
+			return err
+		}
+		lines++
+	}
+	if ctx.Err() != nil {
+		// the client is gone
+		return nil
+	}
+
+	trailer.Total = &lines
+	if responseCtx := GetResponseContext(ctx); responseCtx != nil && responseCtx.Total() != nil {
+		trailer.Total = responseCtx.Total()
+	}
+	b, err := json.Marshal(trailer)
+	if err != nil {
+		return err
+	}
+	return writeLine(b)
+}
@@ -0,0 +1,127 @@
+package handlerx
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// flushRecorder records the body written between two flushes
+type flushRecorder struct {
+	*httptest.ResponseRecorder
+	flushed []string
+	pending string
+}
+
+func (w *flushRecorder) Write(b []byte) (int, error) {
+	w.pending += string(b)
+	return w.ResponseRecorder.Write(b)
+}
+
+func (w *flushRecorder) Flush() {
+	w.flushed = append(w.flushed, w.pending)
+	w.pending = ""
+	w.ResponseRecorder.Flush()
+}
+
+// newTestResponses sends the responses one by one
+func newTestResponses(responses ...*graphql.Response) graphql.ResponseHandler {
+	return func(ctx context.Context) *graphql.Response {
+		if len(responses) == 0 {
+			return nil
+		}
+		response := responses[0]
+		responses = responses[1:]
+		return response
+	}
+}
+
+func TestWriteNDJSON(t *testing.T) {
+	tests := []struct {
+		Name      string
+		Responses []*graphql.Response
+		Total     int64
+		Expected  []string
+	}{
+		{
+			Name: "one flushed line per payload",
+			Responses: []*graphql.Response{
+				{Data: []byte(`{"hostAdded":{"id":"H1"}}`)},
+				{Data: []byte(`{"hostAdded":{"id":"H2"}}`)},
+			},
+			Expected: []string{"{\"id\":\"H1\"}\n", "{\"id\":\"H2\"}\n", "{\"code\":0,\"total\":2}\n"},
+		},
+		{
+			Name: "total set by the resolver",
+			Responses: []*graphql.Response{
+				{Data: []byte(`{"hostAdded":{"id":"H1"}}`)},
+			},
+			Total:    10,
+			Expected: []string{"{\"id\":\"H1\"}\n", "{\"code\":0,\"total\":10}\n"},
+		},
+		{
+			Name: "error ends the stream",
+			Responses: []*graphql.Response{
+				{Data: []byte(`{"hostAdded":{"id":"H1"}}`)},
+				{Errors: gqlerror.List{{Message: "host not found"}}},
+				{Data: []byte(`{"hostAdded":{"id":"H2"}}`)},
+			},
+			Expected: []string{"{\"id\":\"H1\"}\n", "{\"code\":422,\"message\":\"host not found\",\"total\":1}\n"},
+		},
+		{
+			Name:     "empty",
+			Expected: []string{"{\"code\":0,\"total\":0}\n"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			ctx := createResponseContext(context.Background())
+			if tt.Total != 0 {
+				GetResponseContext(ctx).SetTotal(tt.Total)
+			}
+			w := &flushRecorder{ResponseRecorder: httptest.NewRecorder()}
+			require.NoError(t, writeNDJSON(ctx, w, w, newTestResponses(tt.Responses...)))
+			assert.Equal(t, tt.Expected, w.flushed)
+			assert.Empty(t, w.pending)
+		})
+	}
+}
+
+func TestStreamRoute(t *testing.T) {
+	m := newTestSubscriptionMapping()
+	m.SetRouteOptions(RouteOptionsMap{
+		"GET:/todos/events": {Subscription: true, Stream: true},
+	})
+	h := newTestMappingRouter(m, NewPreparedQueryCache(nil))
+
+	w := &flushRecorder{ResponseRecorder: httptest.NewRecorder()}
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/todos/events", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, MediaTypeNDJSON, w.Header().Get("Content-Type"))
+	assert.Equal(t, []string{
+		"", // the headers
+		"{\"done\":false,\"id\":\"T1\",\"text\":\"buy milk\"}\n",
+		"{\"done\":true,\"id\":\"T2\",\"text\":\"walk dog\"}\n",
+		"{\"done\":false,\"id\":\"T3\",\"text\":\"feed cat\"}\n",
+		"{\"code\":0,\"total\":3}\n",
+	}, w.flushed)
+}
+
+func TestStreamQueryRoute(t *testing.T) {
+	// query lists are resolved as a whole, only subscriptions can be streamed
+	m := newTestMapping()
+	m.SetRouteOptions(RouteOptionsMap{
+		"GET:/todos": {Paginate: true, Stream: true},
+	})
+	err := m.Prepare(testSchema)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "GET:/todos: only subscriptions can be streamed")
+}
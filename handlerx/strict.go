@@ -0,0 +1,198 @@
+package handlerx
+
+import (
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SetStrictParams rejects the REST requests of every route with parameters the operation does not
+// take, eg. "?lmit=10", with 400. By default such parameters are dropped and logged, routes can also
+// be made strict one by one, eg. `@http(url: "/todos", strict: true)`.
+func (m *Mapping) SetStrictParams(strict bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.strictParams = strict
+}
+
+// unknownParam is a request parameter the operation does not take, with the closest accepted name
+type unknownParam struct {
+	name       string
+	suggestion string
+}
+
+func (p unknownParam) String() string {
+	if p.suggestion == "" {
+		return p.name
+	}
+	return p.name + " (did you mean " + p.suggestion + "?)"
+}
+
+// unknownParams returns the query and body parameters of a request which are neither arguments
+// of the operation nor fields of its `input` argument, eg. "lmit" or "input.network.vlna", and the
+// names accepted instead. The path parameters and the nested query parameters are always known.
+func (m *Mapping) unknownParams(argTypes StringMap, query url.Values, body map[string]interface{}, options *RouteOptions) ([]unknownParam, []string) {
+	var inputFields StringMap
+	if argType, ok := argTypes["input"]; ok {
+		_, inputType := getUnderlayingArgType(argType)
+		inputFields = m.inputTypes[inputType]
+	}
+
+	// parameter name => type, the query parameters are copied to the input too
+	params := make(StringMap)
+	for k, v := range argTypes {
+		if k != "input" {
+			params[k] = v
+		}
+	}
+	for k, v := range inputFields {
+		params[k] = v
+	}
+	queryParams := []string{fieldsParam}
+	if options.Paginate {
+		queryParams = append(queryParams, limitParam, offsetParam, afterParam, beforeParam)
+	}
+
+	accepted := make([]string, 0, len(params)+len(queryParams))
+	for k := range params {
+		accepted = append(accepted, k)
+	}
+	for _, k := range queryParams {
+		if _, ok := params[k]; !ok {
+			accepted = append(accepted, k)
+		}
+	}
+	sort.Strings(accepted)
+
+	unknown := make([]unknownParam, 0)
+	for k := range query {
+		if _, ok := params[k]; !ok && !contains(queryParams, k) {
+			unknown = append(unknown, unknownParam{k, suggestParam(k, accepted)})
+		}
+	}
+	for k, v := range body {
+		if k == "input" {
+			inner, _ := v.(map[string]interface{})
+			unknown = append(unknown, m.unknownFields(inputFields, "input.", inner)...)
+			continue
+		}
+		argType, ok := params[k]
+		if !ok {
+			unknown = append(unknown, unknownParam{k, suggestParam(k, keys(params))})
+			continue
+		}
+		unknown = append(unknown, m.unknownValueFields(argType, k, v)...)
+	}
+
+	sort.Slice(unknown, func(i, j int) bool { return unknown[i].name < unknown[j].name })
+	return unknown, accepted
+}
+
+// unknownFields returns the keys of a body object which are not fields of its input type, prefixed by path
+func (m *Mapping) unknownFields(fields StringMap, path string, value map[string]interface{}) []unknownParam {
+	unknown := make([]unknownParam, 0)
+	for k, v := range value {
+		fieldType, ok := fields[k]
+		if !ok {
+			unknown = append(unknown, unknownParam{path + k, suggestParam(k, keys(fields))})
+			continue
+		}
+		unknown = append(unknown, m.unknownValueFields(fieldType, path+k, v)...)
+	}
+	return unknown
+}
+
+// unknownValueFields returns the unknown fields of a body value of an input object type, or of a list of them
+func (m *Mapping) unknownValueFields(argType string, path string, value interface{}) []unknownParam {
+	_, underlayingType := getUnderlayingArgType(argType)
+	fields, ok := m.inputTypes[underlayingType]
+	if !ok || m.typeKinds[underlayingType] != "INPUT_OBJECT" {
+		return nil
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return m.unknownFields(fields, path+".", v)
+	case []interface{}:
+		unknown := make([]unknownParam, 0)
+		for i, item := range v {
+			unknown = append(unknown, m.unknownValueFields(underlayingType, path+"["+strconv.Itoa(i)+"]", item)...)
+		}
+		return unknown
+	}
+	return nil
+}
+
+// unknownParamsMessage describes the unknown parameters of a request, eg.
+// "unknown parameters: lmit (did you mean limit?); accepted parameters: done, ids, limit"
+func unknownParamsMessage(unknown []unknownParam, accepted []string) string {
+	params := make([]string, 0, len(unknown))
+	for _, param := range unknown {
+		params = append(params, param.String())
+	}
+
+	msg := "unknown parameters: " + strings.Join(params, ", ")
+	if len(accepted) == 0 {
+		return msg + "; the route takes no parameters"
+	}
+	return msg + "; accepted parameters: " + strings.Join(accepted, ", ")
+}
+
+// suggestParam returns the candidate closest to an unknown name, if close enough to be a typo of it
+func suggestParam(name string, candidates []string) string {
+	best, bestDistance := "", len([]rune(name))/3+1
+	for _, candidate := range candidates {
+		d := editDistance(strings.ToLower(name), strings.ToLower(candidate))
+		if d < bestDistance || d == bestDistance && best != "" && candidate < best {
+			best, bestDistance = candidate, d
+		}
+	}
+	return best
+}
+
+func keys(m StringMap) []string {
+	ret := make([]string, 0, len(m))
+	for k := range m {
+		ret = append(ret, k)
+	}
+	return ret
+}
+
+// editDistance is the number of insertions, deletions, substitutions and transpositions of
+// adjacent characters turning a into b, the optimal string alignment distance
+func editDistance(a string, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	d := make([][]int, len(ra)+1)
+	for i := range d {
+		d[i] = make([]int, len(rb)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			d[i][j] = minInt(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				d[i][j] = minInt(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(ra)][len(rb)]
+}
+
+func minInt(values ...int) int {
+	ret := values[0]
+	for _, v := range values[1:] {
+		if v < ret {
+			ret = v
+		}
+	}
+	return ret
+}
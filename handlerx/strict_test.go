@@ -0,0 +1,96 @@
+package handlerx
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSuggestParam(t *testing.T) {
+	accepted := []string{"done", "ids", "limit", "state"}
+	assert.Equal(t, "limit", suggestParam("lmit", accepted))
+	assert.Equal(t, "limit", suggestParam("Limit", accepted))
+	assert.Equal(t, "state", suggestParam("stat", accepted))
+	assert.Equal(t, "", suggestParam("foo", accepted))
+	assert.Equal(t, "", suggestParam("x", nil))
+	assert.Equal(t, "vlan", suggestParam("vlna", []string{"ip", "vlan"}))
+	assert.Equal(t, 3, editDistance("kitten", "sitting"))
+	assert.Equal(t, 1, editDistance("vlna", "vlan"))
+}
+
+func TestStrictParams(t *testing.T) {
+	m := newTestMapping()
+	m.SetStrictParams(true)
+	h := newTestMappingRouter(m, NewPreparedQueryCache(nil))
+
+	tests := []struct {
+		Name     string
+		Method   string
+		URL      string
+		Body     string
+		Expected string
+	}{
+		{
+			Name: "query", Method: "GET", URL: "/todos?lmit=10&limit=10&fields=id",
+			Expected: "unknown parameters: lmit (did you mean limit?); accepted parameters: after, before, done, fields, ids, limit, offset, state",
+		},
+		{
+			Name: "body", Method: "POST", URL: "/todos", Body: `{"input":{"text":"buy milk","userId":"U1","priorty":1}}`,
+			Expected: "unknown parameters: input.priorty (did you mean priority?); accepted parameters: fields, priority, text, userId",
+		},
+		{
+			Name: "nested body", Method: "PUT", URL: "/hosts/H1", Body: `{"input":{"network":{"vlna":10},"disks":[{"name":"sda","sise":1}]},"force":true}`,
+			Expected: "unknown parameters: force, input.disks[0].sise (did you mean size?), input.network.vlna (did you mean vlan?); accepted parameters: disks, fields, id, network, tags",
+		},
+		{
+			Name: "no parameters", Method: "GET", URL: "/todos/T1?verbose=1",
+			Expected: "unknown parameters: verbose; accepted parameters: fields, id",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(tt.Method, tt.URL, strings.NewReader(tt.Body))
+			r.Header.Set("Content-Type", "application/json")
+			h.ServeHTTP(w, r)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			var resp struct {
+				Code    int    `json:"code"`
+				Message string `json:"message"`
+			}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			assert.Equal(t, http.StatusBadRequest, resp.Code)
+			assert.Equal(t, tt.Expected, resp.Message)
+		})
+	}
+
+	t.Run("known parameters", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", "/todos?limit=10&ids=T1&fields=id", nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("lenient", func(t *testing.T) {
+		m := newTestMapping()
+		m.SetRouteOptions(RouteOptionsMap{"POST:/todos": {StrictParams: true}})
+		h := newTestMappingRouter(m, NewPreparedQueryCache(nil))
+
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", "/todos?lmit=10", nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		w = httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/todos?lmit=10", strings.NewReader(`{"input":{"text":"buy milk","userId":"U1"}}`))
+		r.Header.Set("Content-Type", "application/json")
+		h.ServeHTTP(w, r)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "unknown parameters: lmit;")
+	})
+}
@@ -0,0 +1,155 @@
+package handlerx
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName is the instrumentation name of the spans of handlerx
+const tracerName = "github.com/speedoops/go-gqlrest/handlerx"
+
+// Tracer starts the spans of the requests served by the GET, POST, DELETE, SSE and NDJSON transports with an
+// OpenTelemetry tracer provider: the root span of the request, with the decode, translate, parse, validate and
+// execute spans as children. The requests continue the trace propagated by their headers, read by the propagator.
+// Sampling and exporting are up to the tracer provider, eg. with the default sampler of the SDK the spans of the
+// requests whose traceparent header is not sampled are not exported. See WithTracer.
+type Tracer struct {
+	tracer     trace.Tracer
+	propagator propagation.TextMapPropagator
+}
+
+// TracerOption configures a Tracer
+type TracerOption func(*Tracer)
+
+// WithPropagator sets the propagator reading the trace context of the requests, eg. otel.GetTextMapPropagator(),
+// the W3C trace context if not set
+func WithPropagator(propagator propagation.TextMapPropagator) TracerOption {
+	return func(t *Tracer) {
+		t.propagator = propagator
+	}
+}
+
+// NewTracer returns a tracer starting its spans with provider, eg. the one of the OpenTelemetry SDK:
+//
+//	provider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter))
+//	srv := handlerx.NewDefaultServer(es, handlerx.WithTracer(handlerx.NewTracer(provider)))
+func NewTracer(provider trace.TracerProvider, opts ...TracerOption) *Tracer {
+	t := &Tracer{
+		tracer:     provider.Tracer(tracerName),
+		propagator: propagation.TraceContext{},
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+// start starts the root span of a request, child of the span propagated by its headers if any
+func (t *Tracer) start(ctx context.Context, r *http.Request, start time.Time) (context.Context, trace.Span) {
+	ctx = t.propagator.Extract(ctx, propagation.HeaderCarrier(r.Header))
+	return t.tracer.Start(ctx, "HTTP "+r.Method, trace.WithTimestamp(start), trace.WithSpanKind(trace.SpanKindServer))
+}
+
+// execute starts the execute span of a traced request, the resolvers get it from the context with
+// trace.SpanFromContext. The span of a request which is not traced is a no-op.
+func (l *requestLog) execute(ctx context.Context) (context.Context, trace.Span) {
+	if l.tracer == nil {
+		return ctx, trace.SpanFromContext(context.Background())
+	}
+	l.executed = true
+	return l.tracer.tracer.Start(ctx, "execute", trace.WithAttributes(attribute.String("graphql.operation.name", l.operation())))
+}
+
+// endSpan ends a span, with an error status if the response has errors
+func endSpan(span trace.Span, errs gqlerror.List) {
+	if len(errs) > 0 {
+		msgs := make([]string, 0, len(errs))
+		for _, e := range errs {
+			msgs = append(msgs, e.Message)
+		}
+		span.SetStatus(codes.Error, strings.Join(msgs, "; "))
+	}
+	span.End()
+}
+
+// endTrace ends the root span of a traced request, with the spans of the stages the request went through
+// before the execution: decode, translate for REST requests, parse and validate. The timings of the last
+// two are recorded by gqlgen. If the request failed before the execution, its last stage has an error status.
+func (l *requestLog) endTrace(route string, status int) {
+	if l.tracer == nil {
+		return
+	}
+	end := time.Now()
+
+	type stage struct {
+		name   string
+		timing graphql.TraceTiming
+	}
+	stages := make([]stage, 0, 4)
+
+	decodeEnd := l.translate.Start
+	if decodeEnd.IsZero() && l.rc != nil {
+		decodeEnd = l.rc.Stats.Read.End
+	}
+	stages = append(stages, stage{"decode", graphql.TraceTiming{Start: l.start, End: orTime(decodeEnd, end)}})
+	if !l.translate.Start.IsZero() {
+		stages = append(stages, stage{"translate", graphql.TraceTiming{Start: l.translate.Start, End: orTime(l.translate.End, end)}})
+	}
+	if l.rc != nil && !l.rc.Stats.Parsing.Start.IsZero() {
+		stats := l.rc.Stats
+		stages = append(stages, stage{"parse", graphql.TraceTiming{Start: stats.Parsing.Start, End: orTime(stats.Parsing.End, l.created)}})
+		if !stats.Validation.Start.IsZero() {
+			stages = append(stages, stage{"validate", graphql.TraceTiming{Start: stats.Validation.Start, End: orTime(stats.Validation.End, l.created)}})
+		}
+	}
+
+	root := trace.ContextWithSpan(context.Background(), l.span)
+	for i, stage := range stages {
+		_, span := l.tracer.tracer.Start(root, stage.name, trace.WithTimestamp(stage.timing.Start))
+		if i == len(stages)-1 && !l.executed && status >= http.StatusBadRequest {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+		span.End(trace.WithTimestamp(stage.timing.End))
+	}
+
+	name := l.r.Method + " " + l.r.URL.Path
+	if route != "" {
+		name = l.r.Method + " " + route
+		l.span.SetAttributes(attribute.String("http.route", route))
+	}
+	l.span.SetName(name)
+	l.span.SetAttributes(
+		attribute.String("http.method", l.r.Method),
+		attribute.String("http.target", l.r.URL.Path),
+		attribute.Int("http.status_code", status),
+		attribute.Bool("gqlrest.rest", l.isRESTful),
+		attribute.String("gqlrest.request_id", l.w.Header().Get(RequestIDHeader)),
+	)
+	if l.rc != nil && l.rc.Operation != nil {
+		l.span.SetAttributes(
+			attribute.String("graphql.operation.name", l.rc.Operation.Name),
+			attribute.String("graphql.operation.type", string(l.rc.Operation.Operation)),
+		)
+	}
+	if status >= http.StatusInternalServerError {
+		l.span.SetStatus(codes.Error, http.StatusText(status))
+	}
+	l.span.End(trace.WithTimestamp(end))
+}
+
+// orTime returns t, or the default time if t is not set
+func orTime(t time.Time, defaultTime time.Time) time.Time {
+	if t.IsZero() {
+		return defaultTime
+	}
+	return t
+}
@@ -0,0 +1,203 @@
+package handlerx
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+const testTraceParent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+func newTestTracer(exporter *tracetest.InMemoryExporter) *Tracer {
+	return NewTracer(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+}
+
+func newTestTracingRouter(exporter *tracetest.InMemoryExporter) http.Handler {
+	m := newTestMapping()
+	if err := m.Prepare(testSchema); err != nil {
+		panic(err)
+	}
+
+	tracer := newTestTracer(exporter)
+	srv := handler.New(newTestExecutableSchema())
+	srv.AddTransport(GET{Tracer: tracer})
+	srv.AddTransport(POST{Tracer: tracer})
+	srv.AddTransport(DELETE{Tracer: tracer})
+	srv.SetQueryCache(NewPreparedQueryCache(nil))
+
+	r := chi.NewRouter()
+	m.Bind(ChiBinder{Router: r}, srv)
+	return r
+}
+
+// spansByName indexes the spans of a request, the root span is indexed as "root"
+func spansByName(t *testing.T, spans tracetest.SpanStubs) map[string]tracetest.SpanStub {
+	ret := make(map[string]tracetest.SpanStub, len(spans))
+	for _, span := range spans {
+		name := span.Name
+		if name != "decode" && name != "translate" && name != "parse" && name != "validate" && name != "execute" {
+			name = "root"
+		}
+		require.NotContains(t, ret, name)
+		ret[name] = span
+	}
+	return ret
+}
+
+// spanAttributes indexes the attributes of a span
+func spanAttributes(span tracetest.SpanStub) map[attribute.Key]interface{} {
+	ret := make(map[attribute.Key]interface{}, len(span.Attributes))
+	for _, kv := range span.Attributes {
+		ret[kv.Key] = kv.Value.AsInterface()
+	}
+	return ret
+}
+
+func TestTracing(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	h := newTestTracingRouter(exporter)
+
+	t.Run("REST query", func(t *testing.T) {
+		exporter.Reset()
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "/todos/T1", nil)
+		r.Header.Set("traceparent", testTraceParent)
+		h.ServeHTTP(w, r)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		spans := spansByName(t, exporter.GetSpans())
+		require.Len(t, spans, 6)
+
+		root := spans["root"]
+		attributes := spanAttributes(root)
+		assert.Equal(t, "GET /todos/{id}", root.Name)
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", root.SpanContext.TraceID().String())
+		assert.Equal(t, "00f067aa0ba902b7", root.Parent.SpanID().String())
+		assert.True(t, root.Parent.IsRemote())
+		assert.Equal(t, codes.Unset, root.Status.Code)
+		assert.Equal(t, "/todos/{id}", attributes["http.route"])
+		assert.Equal(t, "GET", attributes["http.method"])
+		assert.Equal(t, int64(http.StatusOK), attributes["http.status_code"])
+		assert.Equal(t, "todo", attributes["graphql.operation.name"])
+		assert.Equal(t, "query", attributes["graphql.operation.type"])
+		assert.Equal(t, true, attributes["gqlrest.rest"])
+		assert.Equal(t, "test-request-id", attributes["gqlrest.request_id"])
+
+		previous := root.StartTime
+		for _, name := range []string{"decode", "translate", "parse", "validate", "execute"} {
+			span := spans[name]
+			assert.Equal(t, root.SpanContext.TraceID(), span.SpanContext.TraceID(), name)
+			assert.Equal(t, root.SpanContext.SpanID(), span.Parent.SpanID(), name)
+			assert.Equal(t, codes.Unset, span.Status.Code, name)
+			// the stages follow each other within the request
+			assert.False(t, span.StartTime.Before(previous), name)
+			assert.False(t, span.EndTime.Before(span.StartTime), name)
+			assert.False(t, span.EndTime.After(root.EndTime), name)
+			previous = span.EndTime
+		}
+	})
+
+	t.Run("not sampled", func(t *testing.T) {
+		exporter.Reset()
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "/todos/T1", nil)
+		r.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+		h.ServeHTTP(w, r)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		assert.Empty(t, exporter.GetSpans())
+	})
+
+	t.Run("GraphQL query", func(t *testing.T) {
+		exporter.Reset()
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", `/todos/T1?query={todo(id:"T1"){id}}`, nil))
+		require.Equal(t, http.StatusOK, w.Code)
+
+		spans := spansByName(t, exporter.GetSpans())
+		assert.NotContains(t, spans, "translate")
+		assert.Contains(t, spans, "execute")
+		root := spans["root"]
+		assert.Equal(t, "GET /todos/T1", root.Name)
+		assert.False(t, root.Parent.IsValid())
+		assert.Equal(t, false, spanAttributes(root)["gqlrest.rest"])
+		assert.NotContains(t, spanAttributes(root), attribute.Key("http.route"))
+	})
+
+	t.Run("translation error", func(t *testing.T) {
+		exporter.Reset()
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", "/todos?fields=unknown", nil))
+		require.Equal(t, http.StatusBadRequest, w.Code)
+
+		spans := spansByName(t, exporter.GetSpans())
+		require.Len(t, spans, 3)
+		assert.Equal(t, codes.Unset, spans["decode"].Status.Code)
+		assert.Equal(t, codes.Error, spans["translate"].Status.Code)
+		// client errors are not errors of the server
+		assert.Equal(t, codes.Unset, spans["root"].Status.Code)
+		assert.Equal(t, int64(http.StatusBadRequest), spanAttributes(spans["root"])["http.status_code"])
+	})
+
+	t.Run("resolver error", func(t *testing.T) {
+		exporter.Reset()
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", "/todos/missing", nil))
+		require.Equal(t, http.StatusInternalServerError, w.Code)
+
+		spans := spansByName(t, exporter.GetSpans())
+		require.Len(t, spans, 6)
+		assert.Equal(t, codes.Unset, spans["validate"].Status.Code)
+		assert.Equal(t, codes.Error, spans["execute"].Status.Code)
+		assert.Equal(t, "todo not found", spans["execute"].Status.Description)
+		assert.Equal(t, codes.Error, spans["root"].Status.Code)
+	})
+}
+
+func TestTracingSSE(t *testing.T) {
+	m := newTestSubscriptionMapping()
+	require.NoError(t, m.Prepare(testSchema))
+	exporter := tracetest.NewInMemoryExporter()
+	srv := handler.New(newTestExecutableSchema())
+	srv.AddTransport(SSE{Tracer: newTestTracer(exporter)})
+	h := chi.NewRouter()
+	m.Bind(ChiBinder{Router: h}, srv)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/todos/events", nil)
+	r.Header.Set("traceparent", testTraceParent)
+	h.ServeHTTP(w, r)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	spans := spansByName(t, exporter.GetSpans())
+	require.Len(t, spans, 6)
+	root := spans["root"]
+	assert.Equal(t, "GET /todos/events", root.Name)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", root.SpanContext.TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", root.Parent.SpanID().String())
+	assert.Equal(t, "subscription", spanAttributes(root)["graphql.operation.type"])
+
+	// the stream is executed until it is closed
+	execute := spans["execute"]
+	assert.Equal(t, root.SpanContext.SpanID(), execute.Parent.SpanID())
+	assert.False(t, execute.EndTime.After(root.EndTime))
+}
+
+func TestTracingDisabled(t *testing.T) {
+	h := newTestRouter(NewPreparedQueryCache(nil))
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/todos/T1", nil)
+	r.Header.Set("traceparent", testTraceParent)
+	h.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
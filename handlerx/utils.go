@@ -3,6 +3,7 @@ package handlerx
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -12,7 +13,6 @@ import (
 	"strings"
 
 	"github.com/99designs/gqlgen/graphql"
-	"github.com/99designs/gqlgen/graphql/errcode"
 	"github.com/vektah/gqlparser/v2/gqlerror"
 )
 
@@ -24,7 +24,7 @@ func jsonDecode(r io.Reader, val interface{}) error {
 
 // Deprecated: writeJSON will Return http status code
 func statusFor(errs gqlerror.List) int {
-	code, _, _ := parseErrCodeFromGqlErrors(errs)
+	code, _, _ := parseErrCodeFromGqlErrors(errs, DefaultErrorMapper)
 	if code == 0 {
 		return http.StatusOK
 	}
@@ -32,16 +32,17 @@ func statusFor(errs gqlerror.List) int {
 	return code
 }
 
-// parseErrCodeFromGqlErrors parse errcode and errMessage from gqlerror.List
-func parseErrCodeFromGqlErrors(errs gqlerror.List) (errCode int, errCodeStr string, errMessage string) {
+// parseErrCodeFromGqlErrors parse errcode and errMessage from gqlerror.List,
+// the code is the HTTP status of the last error with a code, or of the last error
+func parseErrCodeFromGqlErrors(errs gqlerror.List, mapper ErrorMapper) (errCode int, errCodeStr string, errMessage string) {
 	if len(errs) == 0 {
 		return
 	}
 
-	code, msgs := strconv.Itoa(http.StatusUnprocessableEntity), []string{}
+	coded, msgs := errs[len(errs)-1], []string{}
 	for _, e := range errs {
-		if n, ok := e.Extensions["code"]; ok {
-			code, _ = n.(string)
+		if _, ok := e.Extensions["code"]; ok {
+			coded = e
 		}
 
 		if str, ok := e.Extensions["codestr"]; ok {
@@ -56,15 +57,9 @@ func parseErrCodeFromGqlErrors(errs gqlerror.List) (errCode int, errCodeStr stri
 		}
 	}
 
-	if numRegexp.MatchString(code) {
-		// if code is number string
-		errCode, _ = strconv.Atoi(code)
-	} else {
-		if code == errcode.ValidationFailed || code == errcode.ParseFailed {
-			errCode = http.StatusUnprocessableEntity
-		} else {
-			errCode = http.StatusInternalServerError
-		}
+	errCode = mapper.HTTPStatus(coded)
+	if errCode == 0 {
+		errCode = DefaultErrorMapper.HTTPStatus(coded)
 	}
 
 	errMessage = strings.Join(msgs, "; ")
@@ -74,11 +69,14 @@ func parseErrCodeFromGqlErrors(errs gqlerror.List) (errCode int, errCodeStr stri
 // RESTResponse is response struct for RESTful API call
 // @see graphql.Response
 type RESTResponse struct {
-	Code    int             `json:"code"`
-	CodeStr string          `json:"codestr,omitempty"`
-	Message string          `json:"message,omitempty"`
-	Data    json.RawMessage `json:"data"`
-	Total   *int64          `json:"total,omitempty"`
+	Code       int             `json:"code"`
+	CodeStr    string          `json:"codestr,omitempty"`
+	Message    string          `json:"message,omitempty"`
+	Data       json.RawMessage `json:"data"`
+	Total      *int64          `json:"total,omitempty"`
+	Pagination *PageInfo       `json:"pagination,omitempty"`
+	// RequestID is the X-Request-ID of a failed request
+	RequestID string `json:"request_id,omitempty"`
 }
 
 type GraphqlResponse struct {
@@ -89,13 +87,20 @@ type GraphqlResponse struct {
 var numRegexp = regexp.MustCompile(`^\d+$`)
 
 func writeJSON(ctx context.Context, w http.ResponseWriter, r *graphql.Response, isRESTful bool) {
+	responseCtx := GetResponseContext(ctx)
+	if responseCtx != nil {
+		for _, e := range r.Errors {
+			responseCtx.errorCodes = append(responseCtx.errorCodes, errorCode(e))
+		}
+	}
+
 	// 1. For GraphQL API
 	if !isRESTful {
 		response := &GraphqlResponse{
 			Response: r,
 		}
 
-		if responseCtx := GetResponseContext(ctx); responseCtx != nil {
+		if responseCtx != nil {
 			response.Total = responseCtx.Total()
 		}
 
@@ -115,12 +120,15 @@ func writeJSON(ctx context.Context, w http.ResponseWriter, r *graphql.Response,
 		if err := recover(); err != nil {
 			var buf [4096]byte
 			n := runtime.Stack(buf[:], false)
-			dbgPrintf("restful response recover from panic:%v", string(buf[:n]))
+			logf(ctx, LevelError, "restful response recover from panic", "panic", err, "stack", string(buf[:n]))
 
 			r := &RESTResponse{
 				Code:    http.StatusInternalServerError,
 				Message: "unexpected error: unmarshal or write response error",
 			}
+			if responseCtx != nil {
+				r.RequestID = responseCtx.requestID
+			}
 			content, _ := json.Marshal(r)
 			if _, err := w.Write(content); err != nil {
 				panic(err)
@@ -129,40 +137,65 @@ func writeJSON(ctx context.Context, w http.ResponseWriter, r *graphql.Response,
 	}()
 
 	// 2. For RESTful API
+	if responseCtx != nil && responseCtx.mediaType != "" {
+		w.Header().Set("Content-Type", responseCtx.mediaType)
+	}
 	response := &RESTResponse{
 		Code: 0,
 		Data: r.Data,
 	}
 
-	if len(r.Data) > 0 {
-		var m map[string]json.RawMessage
-		err := json.Unmarshal(r.Data, &m)
-		if err != nil {
-			panic(err)
+	if len(r.Errors) > 0 {
+		response.Code, response.CodeStr, response.Message = parseErrCodeFromGqlErrors(r.Errors, errorMapperFor(ctx))
+		if responseCtx != nil {
+			response.RequestID = responseCtx.requestID
 		}
-
-		for _, v := range m {
-			response.Data = v
-			break // it's ok to break here, because graphql response data will have only one top struct member
+		if errorFormatFor(ctx) == ProblemErrorFormat {
+			writeProblem(ctx, w, r.Errors, response.Code, response.CodeStr, response.Message)
+			return
 		}
-	}
-
-	if len(r.Errors) > 0 {
-		response.Code, response.CodeStr, response.Message = parseErrCodeFromGqlErrors(r.Errors)
 		if response.Code != 0 {
 			// 0 means http.StatusOk
 			w.WriteHeader(response.Code)
 		}
+	} else if responseCtx != nil {
+		if links := responseCtx.pageLinks(); links != "" {
+			w.Header().Set("Link", links)
+		}
+		if responseCtx.status != 0 {
+			w.WriteHeader(responseCtx.status)
+			if responseCtx.status == http.StatusNoContent {
+				return
+			}
+		}
 	}
 
-	if responseCtx := GetResponseContext(ctx); responseCtx != nil {
+	if responseCtx != nil {
 		response.Total = responseCtx.Total()
+		response.Pagination = responseCtx.pageInfo()
 	}
 
+	data, err := unwrapData(r.Data)
+	if err != nil {
+		panic(err)
+	}
+	response.Data = data
+
 	b, err := json.Marshal(response)
 	if err != nil {
 		panic(err)
 	}
+	if responseCtx != nil && responseCtx.encoder != nil {
+		// 2.2 negotiated media type other than JSON
+		v, err := decodeJSONValue(b)
+		if err != nil {
+			panic(err)
+		}
+		if err := responseCtx.encoder.Encode(w, v); err != nil {
+			panic(err)
+		}
+		return
+	}
 	_, err = w.Write(b)
 	if err != nil {
 		//logx.Errorf("an io write error occurred: %v", err)
@@ -170,6 +203,23 @@ func writeJSON(ctx context.Context, w http.ResponseWriter, r *graphql.Response,
 	}
 }
 
+// unwrapData returns the value of the top member of the data of a GraphQL response,
+// eg. the list of `{"todos":[...]}`, which is the data of REST responses
+func unwrapData(data json.RawMessage) (json.RawMessage, error) {
+	if len(data) == 0 {
+		return data, nil
+	}
+
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	for _, v := range m {
+		return v, nil // it's ok to return here, because graphql response data will have only one top struct member
+	}
+	return data, nil
+}
+
 func writeJSONError(ctx context.Context, w http.ResponseWriter, code int, isRESTful bool, msg string) {
 	err := gqlerror.Error{
 		Message:    msg,
@@ -184,19 +234,37 @@ func writeJSONErrorf(ctx context.Context, w http.ResponseWriter, code int, isRES
 	writeJSON(ctx, w, &graphql.Response{Errors: gqlerror.List{&err}}, isRESTful)
 }
 
+// writeMappingError writes an error from convertHTTPRequestToGraphQLQuery
+func writeMappingError(ctx context.Context, w http.ResponseWriter, isRESTful bool, prefix string, err error) {
+	var e *mappingError
+	if errors.As(err, &e) {
+		writeErrorHeader(ctx, w, isRESTful, e.code)
+		if len(e.errs) > 0 {
+			writeJSON(ctx, w, &graphql.Response{Errors: e.errs.gqlErrors(e.code)}, isRESTful)
+			return
+		}
+		writeJSONError(ctx, w, e.code, isRESTful, e.msg)
+		return
+	}
+
+	writeErrorHeader(ctx, w, isRESTful, http.StatusBadRequest)
+	writeJSONErrorf(ctx, w, http.StatusUnprocessableEntity, isRESTful, prefix+err.Error())
+}
+
 type Printer interface {
 	Println(v ...interface{})
 	Printf(format string, v ...interface{})
 }
 
-var _printer Printer
-
+// RegisterPrinter prints every log entry, debug ones included, as logfmt lines, see PrinterLogger and RegisterLogger
 func RegisterPrinter(printer Printer) {
-	_printer = printer
+	if printer == nil {
+		RegisterLogger(nil)
+		return
+	}
+	RegisterLogger(PrinterLogger(printer, LevelDebug))
 }
 
 func dbgPrintf(format string, v ...interface{}) {
-	if _, ok := _printer.(Printer); ok {
-		_printer.Printf(format, v...)
-	}
+	logf(context.Background(), LevelDebug, fmt.Sprintf(format, v...))
 }
@@ -12,16 +12,19 @@ import (
 	"github.com/99designs/gqlgen/codegen/config"
 	validator "github.com/speedoops/go-gqlrest/config"
 	"github.com/speedoops/go-gqlrest/restgen"
+	"github.com/speedoops/go-gqlrest/scalar"
 )
 
 var (
 	flagCode              = flag.Bool("code", true, "generate code, default true")
 	flagDoc               = flag.Bool("doc", true, "generate openapi doc")
-	flagValidatorFilePath = flag.String("f", "", "validator config file path")
+	flagValidatorFilePath = flag.String("f", "", "validator config file path, with the validators and the custom scalars")
 	flagPublish           = flag.Bool("publish", false, "publish api to external user")
 	flagYamlFilePath      = flag.String("yaml", "", "api yaml file save dir")
 	flagRestFilePath      = flag.String("rest", "", "rest.go file save path")
 	flagTitle             = flag.String("title", "深信服HCI OpenAPI接口文档", "api yaml doc title")
+	flagDepth             = flag.Int("depth", 0, "max depth of rest field selection, default 0 means unlimited")
+	flagProblem           = flag.Bool("problem", false, "write and document errors as RFC 7807 application/problem+json")
 	verbose               = flag.Bool("verbose", false, "verbose")
 )
 
@@ -41,8 +44,17 @@ func main() {
 
 	options := []api.Option{}
 
+	// validator.yaml, its formats are embedded in rest.go and documented in rest.yaml,
+	// its scalars are documented in rest.yaml and must be registered in the server with handlerx.WithScalar
+	if *flagCode || *flagDoc {
+		validator.InitValidatorConfig(*flagValidatorFilePath)
+	}
+	// the error format of rest.go must match the one documented in rest.yaml
+	validator.SetProblemDetails(*flagProblem)
+
 	// rest.go
 	if *flagCode {
+		validator.SetMaxSelectionDepth(*flagDepth)
 		restfile := path.Join(outputDir, "rest.go")
 		options = append(options, api.AddPlugin(restgen.New(restfile, "Query")))
 	}
@@ -51,9 +63,8 @@ func main() {
 	if *flagDoc {
 		validator.SetYamlFilePath(*flagYamlFilePath)
 		validator.SetDocTitle(*flagTitle)
-		validator.InitValidatorConfig(*flagValidatorFilePath)
 		yamlfile := path.Join(outputDir, "rest.yaml")
-		options = append(options, api.AddPlugin(restgen.NewDocPlugin(yamlfile, "YAML", *flagPublish)))
+		options = append(options, api.AddPlugin(restgen.NewDocPlugin(yamlfile, "YAML", *flagPublish, scalar.FromConfig(validator.GetScalars())...)))
 	}
 
 	err = Generate(cfg, options...)
@@ -3,9 +3,11 @@ package restgen
 import (
 	"fmt"
 	"log"
+	"net/http"
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"syscall"
@@ -14,26 +16,42 @@ import (
 	"github.com/99designs/gqlgen/codegen/config"
 	"github.com/99designs/gqlgen/plugin"
 	validatorConfig "github.com/speedoops/go-gqlrest/config"
+	"github.com/speedoops/go-gqlrest/constraint"
+	"github.com/speedoops/go-gqlrest/scalar"
 	"github.com/vektah/gqlparser/v2/ast"
 	"gopkg.in/yaml.v2"
 )
 
 const (
-	errorResponseObject = "ErrorResponse"
-	uploadObject        = "Upload"
-	ipObject            = "IP"
-	iprangeObject       = "IPRange"
-	macAddressObject    = "MAC"
+	errorResponseObject  = "ErrorResponse"
+	problemErrorObject   = "ProblemError"
+	uploadObject         = "Upload"
+	paginationObject     = "Pagination"
+	fieldsParameter      = "fields"
+	sortParameter        = "sort"
+	sortDescendingSuffix = "_DESC"
+	filterParameter      = "filter"
+	typenameProperty     = "__typename"
+	ndjsonMediaType      = "application/x-ndjson"
+	eventStreamMediaType = "text/event-stream"
 )
 
-func NewDocPlugin(filename string, typename string, isPublished bool) plugin.Plugin {
-	return &DocPlugin{filename: filename, typeName: typename, isPublished: isPublished}
+// NewDocPlugin 创建OpenAPI文档插件，scalars 为自定义标量类型的定义，见 scalar 包
+func NewDocPlugin(filename string, typename string, isPublished bool, scalars ...scalar.Scalar) plugin.Plugin {
+	registry := scalar.NewRegistry()
+	for _, s := range scalars {
+		registry.Register(s)
+	}
+	return &DocPlugin{filename: filename, typeName: typename, isPublished: isPublished, scalars: registry}
 }
 
 type DocPlugin struct {
 	filename    string
 	typeName    string
 	isPublished bool
+	schema      *ast.Schema
+	// 标量类型，为空时仅有内置标量类型
+	scalars *scalar.Registry
 }
 
 var _ plugin.CodeGenerator = &DocPlugin{}
@@ -62,7 +80,7 @@ func (m *DocPlugin) GenerateCode(data *codegen.Data) error {
 	}
 
 	os.MkdirAll(dir, os.ModePerm)
-	return m.GenerateOpenAPIDoc(dir, data.Schema, data.QueryRoot, data.MutationRoot)
+	return m.GenerateOpenAPIDoc(dir, data.Schema, data.QueryRoot, data.MutationRoot, data.SubscriptionRoot)
 }
 
 // 对象（包含入参、枚举、返回值）
@@ -82,9 +100,17 @@ type Object struct {
 	Pattern        *string        `yaml:"pattern,omitempty"`
 	MinItems       *int64         `yaml:"minItems,omitempty"` //切片元素数量限制
 	MaxItems       *int64         `yaml:"maxItems,omitempty"`
+	OneOfSchemas   []*TypeBase    `yaml:"oneOf,omitempty"` // 接口和联合类型的具体类型
+	Discriminator  *Discriminator `yaml:"discriminator,omitempty"`
 	relatedObjects []string       //依赖的对象列表
 }
 
+// 接口和联合类型的类型区分字段
+type Discriminator struct {
+	PropertyName string            `yaml:"propertyName"`
+	Mapping      map[string]string `yaml:"mapping,omitempty"`
+}
+
 // openapi文档对象
 type OpenAPIDoc struct {
 	OpenAPI    string          `yaml:"openapi"`
@@ -162,12 +188,15 @@ type APIRequestBody struct {
 }
 
 type APIResponse struct {
-	Content     *APIResponseContent `yaml:"content"`
+	Content     *APIResponseContent `yaml:"content,omitempty"`
 	Description string              `yaml:"description"`
 }
 
 type APIResponseContent struct {
-	Json *SchemaObject `yaml:"application/json"`
+	Json    *SchemaObject `yaml:"application/json,omitempty"`
+	Problem *SchemaObject `yaml:"application/problem+json,omitempty"`
+	// 其他媒体类型，如 text/csv
+	Others map[string]*SchemaObject `yaml:",inline"`
 }
 
 type SchemaObject struct {
@@ -210,23 +239,37 @@ type Component struct {
 	Schemas map[string]*Object `yaml:"schemas"`
 }
 
-// formatVariableType 将schema的类型转换为OpenAPI类型
-func formatVariableType(typ string) (formatType, formatter string) {
+// formatVariableType 将schema的类型转换为OpenAPI类型，标量类型见 NewDocPlugin
+func (m *DocPlugin) formatVariableType(typ string) (formatType, formatter string) {
 	length := len(typ)
 	if string(typ[length-1]) == "!" {
-		return formatVariableType(string(typ[:length-1]))
-	} else if typ == "String" || typ == "ID" || typ == "Time" {
-		return "string", ""
-	} else if typ == "Int" {
-		return "integer", "int64"
-	} else if typ == "Boolean" {
-		return "boolean", ""
-	} else if typ == "Float" {
-		return "number", "double"
+		return m.formatVariableType(string(typ[:length-1]))
+	}
+	if s, ok := m.scalarType(typ); ok {
+		return s.Type, s.Format
 	}
 	return typ, ""
 }
 
+// scalarType 返回标量类型的定义，未注册的自定义标量类型为字符串
+func (m *DocPlugin) scalarType(typ string) (*scalar.Scalar, bool) {
+	var def *ast.Definition
+	if m.schema != nil {
+		def = m.schema.Types[typ]
+	}
+	if def != nil && def.Kind != ast.Scalar {
+		return nil, false
+	}
+	if s, ok := m.scalars.Lookup(typ); ok {
+		return s, true
+	}
+	// Upload 为 multipart 上传对象
+	if def != nil && typ != uploadObject {
+		return scalar.Default(typ), true
+	}
+	return nil, false
+}
+
 // isBaseType 判断转换之后的类型是否为基础类型
 func (m *DocPlugin) isBaseType(typ string) bool {
 	return typ == "string" || typ == "integer" || typ == "boolean" || typ == "number"
@@ -244,6 +287,10 @@ func (m *DocPlugin) isRequired(typ string) bool {
 
 // 生成错误返回值对象
 func (m *DocPlugin) generateErrorResponse() *Object {
+	if validatorConfig.GetProblemDetails() {
+		return m.generateProblemResponse()
+	}
+
 	return &Object{
 		name:        errorResponseObject,
 		Type:        "object",
@@ -262,6 +309,78 @@ func (m *DocPlugin) generateErrorResponse() *Object {
 	}
 }
 
+// generateProblemResponse 生成 RFC 7807 格式的错误返回值对象
+func (m *DocPlugin) generateProblemResponse() *Object {
+	return &Object{
+		name:           errorResponseObject,
+		Type:           "object",
+		Description:    "RFC 7807 problem details",
+		relatedObjects: []string{problemErrorObject},
+		Properties: []yaml.MapItem{
+			{Key: "type", Value: &SchemaType{
+				Type:        "string",
+				Format:      "uri-reference",
+				Description: "问题类型",
+			}},
+			{Key: "title", Value: &SchemaType{
+				Type:        "string",
+				Description: "问题摘要",
+			}},
+			{Key: "status", Value: &SchemaType{
+				Type:        "integer",
+				Format:      "int64",
+				Description: "http status code",
+			}},
+			{Key: "detail", Value: &SchemaType{
+				Type:        "string",
+				Description: "错误消息",
+			}},
+			{Key: "instance", Value: &SchemaType{
+				Type:        "string",
+				Format:      "uri-reference",
+				Description: "请求路径",
+			}},
+			{Key: "codestr", Value: &SchemaType{
+				Type:        "string",
+				Description: "错误码",
+			}},
+			{Key: "errors", Value: &SchemaType{
+				Type:        "array",
+				Description: "GraphQL错误列表",
+				Items: &TypeBase{
+					Ref: "#/components/schemas/" + problemErrorObject,
+				},
+			}},
+		},
+	}
+}
+
+// generateProblemErrorObject 生成 problem document 中的 GraphQL 错误对象
+func (m *DocPlugin) generateProblemErrorObject() *Object {
+	return &Object{
+		name:        problemErrorObject,
+		Type:        "object",
+		Description: "GraphQL error",
+		Properties: []yaml.MapItem{
+			{Key: "message", Value: &SchemaType{
+				Type:        "string",
+				Description: "错误消息",
+			}},
+			{Key: "path", Value: &SchemaType{
+				Type:        "array",
+				Description: "错误路径",
+				Items: &TypeBase{
+					Type: "string",
+				},
+			}},
+			{Key: "code", Value: &SchemaType{
+				Type:        "string",
+				Description: "错误码",
+			}},
+		},
+	}
+}
+
 // generateUploadObject生成上传对象
 func (m *DocPlugin) generateUploadObject() *Object {
 	return &Object{
@@ -290,58 +409,15 @@ func (m *DocPlugin) generateUploadObject() *Object {
 	}
 }
 
-// generateIPObject生成IP对象(ipv4 ipv6)
-// 2001:0db8:3c4d:0015:0000:0000:1a2f:1a2b
-func (m *DocPlugin) generateIPObject() *Object {
-	minLength := int64(0)
-	maxLength := int64(39)
-	return &Object{
-		name:        ipObject,
-		Type:        "string",
-		Format:      "ip",
-		Description: "ip object",
-		MinLength:   &minLength,
-		MaxLength:   &maxLength,
-	}
-}
-
-// generatedIPRangeObject 生成IPRange对象
-func (m *DocPlugin) generateIPRangeObject() *Object {
-	minLength := int64(0)
-	maxLength := int64(79)
-	return &Object{
-		name:        iprangeObject,
-		Type:        "string",
-		Format:      "iprange",
-		Description: "ip range object",
-		MinLength:   &minLength,
-		MaxLength:   &maxLength,
-	}
-}
-
-// generateMacAddressObject 生成mac地址对象
-func (m *DocPlugin) generateMacAddressObject() *Object {
-	minLength := int64(0)
-	maxLength := int64(59)
-	return &Object{
-		name:        macAddressObject,
-		Type:        "string",
-		Format:      "mac",
-		Description: "mac address object",
-		MinLength:   &minLength,
-		MaxLength:   &maxLength,
-	}
-}
-
 // GenerateOpenAPIDoc 生成openapi文档
-func (m *DocPlugin) GenerateOpenAPIDoc(yamlDir string, schema *ast.Schema, query *codegen.Object, mutation *codegen.Object) error {
+func (m *DocPlugin) GenerateOpenAPIDoc(yamlDir string, schema *ast.Schema, query *codegen.Object, mutation *codegen.Object, subscription *codegen.Object) error {
+	m.schema = schema
 	apis := make(map[string]*API)
 	objects := make(map[string]*Object)
 	objects[errorResponseObject] = m.generateErrorResponse()
+	objects[problemErrorObject] = m.generateProblemErrorObject()
 	objects[uploadObject] = m.generateUploadObject()
-	objects[ipObject] = m.generateIPObject()
-	objects[iprangeObject] = m.generateIPRangeObject()
-	objects[macAddressObject] = m.generateMacAddressObject()
+	objects[paginationObject] = m.generatePaginationObject()
 
 	for _, typ := range schema.Types {
 		if strings.HasPrefix(typ.Name, "__") {
@@ -349,9 +425,14 @@ func (m *DocPlugin) GenerateOpenAPIDoc(yamlDir string, schema *ast.Schema, query
 		}
 
 		if typ.Kind == ast.Object {
-			if !(typ.Name == "Mutation" || typ.Name == "Query") {
+			if !(typ.Name == "Mutation" || typ.Name == "Query" || typ.Name == "Subscription") {
 				objects[typ.Name] = m.parseObject(typ)
+				if len(schema.GetImplements(typ)) > 0 {
+					m.addTypenameProperty(objects[typ.Name])
+				}
 			}
+		} else if typ.Kind == ast.Interface || typ.Kind == ast.Union {
+			objects[typ.Name] = m.parseAbstractType(schema, typ)
 		} else if typ.Kind == ast.Enum {
 			objects[typ.Name] = m.parseEnum(typ)
 		} else if typ.Kind == ast.InputObject {
@@ -361,6 +442,7 @@ func (m *DocPlugin) GenerateOpenAPIDoc(yamlDir string, schema *ast.Schema, query
 
 	apis = m.parseAPI(query, apis, objects, "GET")
 	apis = m.parseAPI(mutation, apis, objects, "POST")
+	apis = m.parseAPI(subscription, apis, objects, "GET")
 
 	apiTagMap := make(map[string][]*API)
 	for _, api := range apis {
@@ -537,10 +619,20 @@ func (m *DocPlugin) parseAPI(data *codegen.Object, apis map[string]*API, compone
 
 		responseName := strings.Title(field.Name) + "Response"
 		obj.RequestBody = m.parseRequestBody(field)
-		obj.Responses = m.generateAPIResponse(responseName)
+		errorStatuses := GetErrorStatuses(field)
+		if IsIdempotent(field) && method != "GET" {
+			// 幂等键仍在处理中
+			errorStatuses = append(errorStatuses, http.StatusConflict)
+		}
+		obj.Responses = m.generateAPIResponse(responseName, GetStatus(field), errorStatuses, GetProduces(field))
 
 		schema := m.parseType(field.Name, field.FieldDefinition.Type, nil)
 		schema.Description = "响应数据"
+		if IsSubscription(field) && IsStreamed(field) {
+			m.setStreamResponse(obj.Responses, field.FieldDefinition.Type)
+		} else if IsSubscription(field) {
+			m.setEventStreamResponse(obj.Responses, responseName)
+		}
 
 		//记录关联对象
 		api.relatedObjecs = append(api.relatedObjecs, responseName, errorResponseObject)
@@ -569,12 +661,37 @@ func (m *DocPlugin) parseAPI(data *codegen.Object, apis map[string]*API, compone
 			},
 		}
 
+		if IsPaginated(field) {
+			// 分页接口，返回总数和分页信息
+			responseObj.Properties = append(responseObj.Properties,
+				yaml.MapItem{Key: "total", Value: &SchemaType{
+					Type:        "integer",
+					Format:      "int64",
+					Description: "总数",
+				}},
+				yaml.MapItem{Key: "pagination", Value: &SchemaType{
+					Ref: "#/components/schemas/" + paginationObject,
+				}},
+			)
+			api.relatedObjecs = append(api.relatedObjecs, paginationObject)
+		}
+
 		// 注册返回值一级域
 		components[responseName] = responseObj
 
 		if obj.RequestBody == nil {
 			// requestBody为nil,才遍历args参数
 			for _, arg := range field.Args {
+				if def := m.schema.Types[arg.Type.Name()]; arg.Name == filterParameter && def != nil && def.Kind == ast.InputObject {
+					// 过滤参数展开为 filter[name]=foo 形式
+					obj.Parameters = append(obj.Parameters, m.parseFilterParameters(filterParameter, def, api, nil)...)
+					continue
+				}
+				if arg.Name == sortParameter {
+					obj.Parameters = append(obj.Parameters, m.parseSortParameter(arg.Type, arg.Description))
+					continue
+				}
+
 				in := "query"
 				required := m.isRequired(arg.Type.String())
 				variable := fmt.Sprintf("{%s}", arg.Name)
@@ -659,11 +776,224 @@ func (m *DocPlugin) parseAPI(data *codegen.Object, apis map[string]*API, compone
 				})
 			}
 		}
+
+		if method == "GET" && len(field.TypeReference.Definition.Fields) > 0 && field.Arguments.ForName(fieldsParameter) == nil {
+			obj.Parameters = append(obj.Parameters, m.generateFieldsParameter())
+		}
+
+		if IsPaginated(field) {
+			for _, param := range m.generatePaginationParameters() {
+				if field.Arguments.ForName(param.Name) == nil || obj.RequestBody != nil {
+					obj.Parameters = append(obj.Parameters, param)
+				}
+			}
+		}
+
+		if IsSubscription(field) {
+			obj.Parameters = append(obj.Parameters, m.generateLastEventIDParameter())
+		}
+		if IsIdempotent(field) && method != "GET" {
+			obj.Parameters = append(obj.Parameters, m.generateIdempotencyKeyParameter())
+		}
 	}
 
 	return apis
 }
 
+// generateIdempotencyKeyParameter 生成幂等接口的幂等键参数
+func (m *DocPlugin) generateIdempotencyKeyParameter() *APIParameter {
+	description := "幂等键，相同幂等键和相同请求的重试返回首次请求的响应，相同幂等键的不同请求返回 422"
+	return &APIParameter{
+		In:          "header",
+		Name:        "Idempotency-Key",
+		Required:    false,
+		Description: description,
+		Schema: &SchemaType{
+			Type:        "string",
+			Description: description,
+		},
+	}
+}
+
+// generateLastEventIDParameter 生成订阅接口的断线重连参数
+func (m *DocPlugin) generateLastEventIDParameter() *APIParameter {
+	description := "断线重连时客户端收到的最后一个事件的 id，服务端从其后的事件开始推送"
+	return &APIParameter{
+		In:          "header",
+		Name:        "Last-Event-ID",
+		Required:    false,
+		Description: description,
+		Schema: &SchemaType{
+			Type:        "string",
+			Description: description,
+		},
+	}
+}
+
+// generatePaginationParameters 生成分页参数
+func (m *DocPlugin) generatePaginationParameters() []*APIParameter {
+	params := []struct {
+		name        string
+		typ         string
+		description string
+	}{
+		{"limit", "integer", "每页数量"},
+		{"offset", "integer", "起始偏移量，不能与 after、before 同时使用"},
+		{"after", "string", "游标，返回该游标之后的数据，取值为上一页返回的 next_cursor"},
+		{"before", "string", "游标，返回该游标之前的数据，取值为上一页返回的 prev_cursor"},
+	}
+
+	ret := make([]*APIParameter, 0, len(params))
+	for _, param := range params {
+		schema := &SchemaType{
+			Type:        param.typ,
+			Description: param.description,
+		}
+		if param.typ == "integer" {
+			minimum := float64(0)
+			schema.Format = "int64"
+			schema.Minimum = &minimum
+		}
+		ret = append(ret, &APIParameter{
+			In:          "query",
+			Name:        param.name,
+			Required:    false,
+			Description: param.description,
+			Schema:      schema,
+		})
+	}
+	return ret
+}
+
+// generatePaginationObject 生成分页信息对象
+func (m *DocPlugin) generatePaginationObject() *Object {
+	return &Object{
+		name:        paginationObject,
+		Type:        "object",
+		Description: "pagination info",
+		Properties: []yaml.MapItem{
+			{Key: "limit", Value: &SchemaType{
+				Type:        "integer",
+				Format:      "int64",
+				Description: "每页数量",
+			}},
+			{Key: "offset", Value: &SchemaType{
+				Type:        "integer",
+				Format:      "int64",
+				Description: "起始偏移量",
+			}},
+			{Key: "next_cursor", Value: &SchemaType{
+				Type:        "string",
+				Description: "下一页游标",
+			}},
+			{Key: "prev_cursor", Value: &SchemaType{
+				Type:        "string",
+				Description: "上一页游标",
+			}},
+		},
+	}
+}
+
+// parseFilterParameters 将过滤输入对象展开为 filter[name]、filter[status][in] 形式的查询参数
+func (m *DocPlugin) parseFilterParameters(name string, def *ast.Definition, api *API, visited []string) []*APIParameter {
+	visited = append(visited, def.Name)
+
+	params := make([]*APIParameter, 0)
+	for _, field := range def.Fields {
+		key := name + "[" + field.Name + "]"
+		if inner := m.schema.Types[field.Type.Name()]; inner != nil && inner.Kind == ast.InputObject {
+			if !m.isArray(field.Type.String()) && !contains(visited, inner.Name) {
+				params = append(params, m.parseFilterParameters(key, inner, api, visited)...)
+			}
+			continue
+		}
+
+		schema := m.parseType(field.Name, field.Type, &field.Directives)
+		schema.Description = field.Description
+		if m.isArray(field.Type.String()) {
+			schema.Description = strings.TrimSpace(schema.Description + " 多个值以逗号分隔")
+		}
+
+		// 记录关联对象
+		if len(schema.relatedObjects) > 0 {
+			api.relatedObjecs = append(api.relatedObjecs, schema.relatedObjects...)
+		}
+
+		params = append(params, &APIParameter{
+			In:          "query",
+			Name:        key,
+			Required:    false,
+			Description: schema.Description,
+			Schema:      schema,
+		})
+	}
+	return params
+}
+
+// parseSortParameter 生成排序参数，如 sort=-created,name。输入对象的字段均可降序，
+// 枚举值 X 仅在枚举中有 X_DESC 时可降序，与 handlerx 的 parseSortParam 一致
+func (m *DocPlugin) parseSortParameter(typ *ast.Type, description string) *APIParameter {
+	keys := make([]string, 0)
+	single := false
+	if def := m.schema.Types[typ.Name()]; def != nil {
+		if def.Kind == ast.InputObject {
+			for _, field := range def.Fields {
+				keys = append(keys, field.Name, "-"+field.Name)
+			}
+		} else if def.Kind == ast.Enum {
+			single = typ.Elem == nil
+			for _, value := range def.EnumValues {
+				if strings.HasSuffix(value.Name, sortDescendingSuffix) &&
+					def.EnumValues.ForName(strings.TrimSuffix(value.Name, sortDescendingSuffix)) != nil {
+					continue
+				}
+				keys = append(keys, value.Name)
+				if def.EnumValues.ForName(value.Name+sortDescendingSuffix) != nil {
+					keys = append(keys, "-"+value.Name)
+				}
+			}
+		}
+	}
+
+	if description != "" {
+		description += "\n\n"
+	}
+	if single {
+		description += "排序字段，字段前加 - 表示降序，如 -created"
+	} else {
+		description += "排序字段，多个字段以逗号分隔，字段前加 - 表示降序，如 -created,name"
+	}
+	if len(keys) > 0 {
+		description += "\n\n可选字段: " + strings.Join(keys, ", ")
+	}
+
+	return &APIParameter{
+		In:          "query",
+		Name:        sortParameter,
+		Required:    false,
+		Description: description,
+		Schema: &SchemaType{
+			Type:        "string",
+			Description: description,
+		},
+	}
+}
+
+// generateFieldsParameter 生成返回字段过滤参数
+func (m *DocPlugin) generateFieldsParameter() *APIParameter {
+	description := "返回字段列表，默认返回全部字段，如 id,name,user{id} 或 id,user.id"
+	return &APIParameter{
+		In:          "query",
+		Name:        fieldsParameter,
+		Required:    false,
+		Description: description,
+		Schema: &SchemaType{
+			Type:        "string",
+			Description: description,
+		},
+	}
+}
+
 func getPropertiesValue(list []yaml.MapItem, key interface{}) (*SchemaType, bool) {
 	for _, item := range list {
 		if item.Key == key {
@@ -696,32 +1026,116 @@ func (m *DocPlugin) parseRequestBody(field *codegen.Field) *APIRequestBody {
 	}
 }
 
-// generateDefaultResponse 生成默认返回值
-func (m *DocPlugin) generateAPIResponse(responseName string) map[string]*APIResponse {
-	return map[string]*APIResponse{
-		"200": {
-			Content: &APIResponseContent{
-				Json: &SchemaObject{
-					Schema: &SchemaType{
-						Ref: "#/components/schemas/" + responseName,
-					},
-				},
+// setStreamResponse 流式订阅接口以 application/x-ndjson 返回，每行为一个事件的数据，
+// 最后一行为包含错误码、错误消息和总数的结尾记录
+func (m *DocPlugin) setStreamResponse(responses map[string]*APIResponse, typ *ast.Type) {
+	response := responses[strconv.Itoa(http.StatusOK)]
+	if response == nil {
+		return
+	}
+
+	response.Content = &APIResponseContent{
+		Others: map[string]*SchemaObject{
+			ndjsonMediaType: {
+				Schema: m.parseType(typ.Name(), typ, nil),
 			},
-			Description: "OK",
 		},
-		"default": {
-			Content: &APIResponseContent{
-				Json: &SchemaObject{
-					Schema: &SchemaType{
-						Ref: "#/components/schemas/" + errorResponseObject,
-					},
+	}
+}
+
+// setEventStreamResponse 订阅接口以 text/event-stream 返回，每个事件的数据为一个响应对象
+func (m *DocPlugin) setEventStreamResponse(responses map[string]*APIResponse, responseName string) {
+	response := responses[strconv.Itoa(http.StatusOK)]
+	if response == nil {
+		return
+	}
+
+	response.Content = &APIResponseContent{
+		Others: map[string]*SchemaObject{
+			eventStreamMediaType: {
+				Schema: &SchemaType{
+					Ref: "#/components/schemas/" + responseName,
 				},
 			},
-			Description: "Error",
 		},
 	}
 }
 
+// generateAPIResponse 生成返回值，包括成功状态码，以及参数错误、校验失败、内部错误和接口声明的错误状态码，
+// 成功返回值除JSON外还包括接口声明的其他媒体类型
+func (m *DocPlugin) generateAPIResponse(responseName string, status int, errorStatuses []int, produces []string) map[string]*APIResponse {
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	responses := make(map[string]*APIResponse)
+	if status == http.StatusNoContent {
+		responses[strconv.Itoa(status)] = &APIResponse{
+			Description: http.StatusText(status),
+		}
+	} else {
+		content := &APIResponseContent{
+			Json: &SchemaObject{
+				Schema: &SchemaType{
+					Ref: "#/components/schemas/" + responseName,
+				},
+			},
+		}
+		for _, mediaType := range produces {
+			if content.Others == nil {
+				content.Others = make(map[string]*SchemaObject)
+			}
+			if isStructuredMediaType(mediaType) {
+				content.Others[mediaType] = content.Json
+			} else {
+				content.Others[mediaType] = &SchemaObject{Schema: textResponseSchema(mediaType)}
+			}
+		}
+		responses[strconv.Itoa(status)] = &APIResponse{
+			Content:     content,
+			Description: http.StatusText(status),
+		}
+	}
+
+	errorResponse := func(description string) *APIResponse {
+		content := &SchemaObject{
+			Schema: &SchemaType{
+				Ref: "#/components/schemas/" + errorResponseObject,
+			},
+		}
+		if validatorConfig.GetProblemDetails() {
+			return &APIResponse{Content: &APIResponseContent{Problem: content}, Description: description}
+		}
+		return &APIResponse{Content: &APIResponseContent{Json: content}, Description: description}
+	}
+	statuses := append([]int{http.StatusBadRequest, http.StatusUnprocessableEntity, http.StatusInternalServerError}, errorStatuses...)
+	for _, errorStatus := range statuses {
+		responses[strconv.Itoa(errorStatus)] = errorResponse(http.StatusText(errorStatus))
+	}
+	responses["default"] = errorResponse("Error")
+
+	return responses
+}
+
+// isStructuredMediaType 判断媒体类型是否与JSON结构相同，如 application/yaml、application/msgpack，
+// 结构相同的媒体类型复用JSON的返回值结构
+func isStructuredMediaType(mediaType string) bool {
+	switch mediaType {
+	case "application/yaml", "application/x-yaml", "application/msgpack", "application/x-msgpack":
+		return true
+	}
+	return strings.HasSuffix(mediaType, "+json") || strings.HasSuffix(mediaType, "+yaml")
+}
+
+// textResponseSchema 生成非结构化媒体类型的返回值，如 text/csv 为字符串
+func textResponseSchema(mediaType string) *SchemaType {
+	schema := &SchemaType{Type: "string"}
+	if mediaType == "text/csv" {
+		schema.Description = "CSV，首行为列名，每个元素一行，嵌套字段的列名为点分路径，如 owner.name"
+	}
+	return schema
+}
+
 func (m *DocPlugin) parseEnum(typ *ast.Definition) *Object {
 	enum := &Object{
 		name:        typ.Name,
@@ -775,9 +1189,50 @@ func (m *DocPlugin) parseObject(typ *ast.Definition) *Object {
 	return obj
 }
 
+// parseAbstractType 解析接口和联合类型，通过 __typename 区分具体类型
+func (m *DocPlugin) parseAbstractType(schema *ast.Schema, typ *ast.Definition) *Object {
+	obj := &Object{
+		name:        typ.Name,
+		Type:        "object",
+		Description: typ.Description,
+		Discriminator: &Discriminator{
+			PropertyName: typenameProperty,
+			Mapping:      make(map[string]string),
+		},
+	}
+
+	possibleTypes := make([]string, 0)
+	for _, possibleType := range schema.GetPossibleTypes(typ) {
+		if possibleType.Kind == ast.Object {
+			possibleTypes = append(possibleTypes, possibleType.Name)
+		}
+	}
+	sort.Strings(possibleTypes)
+
+	for _, name := range possibleTypes {
+		ref := "#/components/schemas/" + name
+		obj.OneOfSchemas = append(obj.OneOfSchemas, &TypeBase{Ref: ref})
+		obj.Discriminator.Mapping[name] = ref
+		// 记录关联对象
+		obj.relatedObjects = append(obj.relatedObjects, name)
+	}
+
+	return obj
+}
+
+// addTypenameProperty 为接口和联合类型的具体类型添加 __typename 字段
+func (m *DocPlugin) addTypenameProperty(obj *Object) {
+	schema := &SchemaType{
+		Type:        "string",
+		Description: "具体类型名称",
+	}
+	obj.Required = append([]string{typenameProperty}, obj.Required...)
+	obj.Properties = append([]yaml.MapItem{{Key: typenameProperty, Value: schema}}, obj.Properties...)
+}
+
 func (m *DocPlugin) parseType(typName string, typObj *ast.Type, directives *ast.DirectiveList) *SchemaType {
 	schema := &SchemaType{}
-	typ, format := formatVariableType(typObj.Name())
+	typ, format := m.formatVariableType(typObj.Name())
 
 	if m.isArray(typObj.String()) {
 		// 数组
@@ -812,158 +1267,47 @@ func (m *DocPlugin) parseType(typName string, typObj *ast.Type, directives *ast.
 		}
 	}
 
-	var validator *validator
-	var isStringSlice bool
+	var rule *constraint.Rule
 	if directives != nil {
-		if numberValidator := directives.ForName("constraintNumber"); numberValidator != nil {
-			validator = m.parseConstraintDirectiver(typName, numberValidator)
-		} else if stringValidator := directives.ForName("constraintString"); stringValidator != nil {
-			validator = m.parseConstraintDirectiver(typName, stringValidator)
-		} else if sliceValidator := directives.ForName("constraintSlice"); sliceValidator != nil {
-			validator = m.parseConstraintDirectiver(typName, sliceValidator)
-		} else if stringSliceValidator := directives.ForName("constraintStringSlice"); stringSliceValidator != nil {
-			validator = m.parseConstraintDirectiver(typName, stringSliceValidator)
-			isStringSlice = true
-		}
-	}
-
-	if validator != nil {
-		schema.Maximum = validator.Maximum
-		schema.Minimum = validator.Minimum
-		schema.OneOf = validator.Oneof
-
-		schema.MaxItems = validator.MaxItems
-		schema.MinItems = validator.MinItems
-
-		if isStringSlice {
-			schema.Items.Pattern = validator.Pattern
-			schema.Items.MinLength = validator.MinLength
-			schema.Items.MaxLength = validator.MaxLength
-		} else {
-			schema.Pattern = validator.Pattern
-			schema.MaxLength = validator.MaxLength
-			schema.MinLength = validator.MinLength
-		}
-	}
-
-	return schema
-}
-
-type validator struct {
-	Minimum   *float64 //Number取值限制
-	Maximum   *float64
-	Oneof     []float64 //数字枚举
-	MinLength *int64    //字符串长度限制
-	MaxLength *int64
-	fomat     *string
-	Pattern   *string
-	MinItems  *int64 //切片元素数量限制
-	MaxItems  *int64
-}
-
-// 解析Constraint系列指令
-func (m *DocPlugin) parseConstraintDirectiver(variableName string, directive *ast.Directive) *validator {
-	obj := &validator{}
-	minimum := directive.Arguments.ForName("min")
-	if minimum == nil {
-		minimum = directive.Arguments.ForName("minimum")
+		rule = constraint.ForDirectives(typName, *directives, constraint.FormatsOf(validatorConfig.GetValidators()))
 	}
 
-	if minimum != nil {
-		num, err := strconv.ParseFloat(minimum.Value.String(), 64)
-		if err != nil {
-			log.Printf("parse variable:%v minimum value:%v to float error:%v", variableName, minimum.Value.String(), err.Error())
-		} else {
-			obj.Minimum = &num
-		}
-	}
+	if rule != nil {
+		schema.Maximum = rule.Maximum
+		schema.Minimum = rule.Minimum
+		schema.OneOf = rule.OneOf
 
-	maximum := directive.Arguments.ForName("max")
-	if maximum == nil {
-		maximum = directive.Arguments.ForName("maximum")
-	}
+		schema.MaxItems = rule.MaxItems
+		schema.MinItems = rule.MinItems
 
-	if maximum != nil {
-		num, err := strconv.ParseFloat(maximum.Value.String(), 64)
-		if err != nil {
-			log.Printf("parse variable:%v maximum value:%v to float error:%v", variableName, maximum.Value.String(), err.Error())
+		if rule.Items {
+			schema.Items.Pattern = rule.Pattern
+			schema.Items.MinLength = rule.MinLength
+			schema.Items.MaxLength = rule.MaxLength
 		} else {
-			obj.Maximum = &num
+			schema.Pattern = rule.Pattern
+			schema.MaxLength = rule.MaxLength
+			schema.MinLength = rule.MinLength
 		}
 	}
 
-	oneOf := directive.Arguments.ForName("oneOf")
-	if oneOf != nil {
-		v := oneOf.Value.String()
-		v = v[1 : len(v)-1]
-		values := strings.Split(v, ",")
-		array := make([]float64, 0, len(values))
-		for _, a := range values {
-			num, err := strconv.ParseFloat(a, 64)
-			if err != nil {
-				log.Printf("parse variable:%v oneOf value:%v to float error:%v", variableName, v, err.Error())
-			} else {
-				array = append(array, num)
-			}
+	// 标量类型的取值限制，约束指令优先
+	if s, ok := m.scalarType(typObj.Name()); ok && s.Type == scalar.TypeString {
+		pattern, minLength, maxLength := &schema.Pattern, &schema.MinLength, &schema.MaxLength
+		if schema.Items != nil {
+			pattern, minLength, maxLength = &schema.Items.Pattern, &schema.Items.MinLength, &schema.Items.MaxLength
 		}
-		if len(array) > 0 {
-			obj.Oneof = array
+		if *pattern == nil && s.Pattern != "" {
+			p := s.Pattern
+			*pattern = &p
 		}
-	}
-
-	if format := directive.Arguments.ForName("format"); format != nil {
-		// 读取外部配置
-		formatValue := format.Value.String()
-		obj.fomat = &formatValue
-		va := validatorConfig.GetValidatorByFormat(formatValue)
-		if va != nil {
-			obj.Pattern = va.Pattern
-			obj.MinLength = va.MinLength
-			obj.MaxLength = va.MaxLength
-		} else {
-			log.Printf("WARNING: format '%s' not found in validator.yaml.\n", formatValue)
+		if *minLength == nil {
+			*minLength = s.MinLength
 		}
-	}
-
-	minLength := directive.Arguments.ForName("minLength")
-	if minLength != nil {
-		num, err := strconv.ParseInt(minLength.Value.String(), 10, 64)
-		if err != nil {
-			log.Printf("parse variable:%v minLength value:%v to int error:%v", variableName, minLength.Value.String(), err.Error())
-		} else {
-			obj.MinLength = &num
-		}
-	}
-
-	maxLength := directive.Arguments.ForName("maxLength")
-	if maxLength != nil {
-		num, err := strconv.ParseInt(maxLength.Value.String(), 10, 64)
-		if err != nil {
-			log.Printf("parse variable:%v maxLength value:%v to int error:%v", variableName, maxLength.Value.String(), err.Error())
-		} else {
-			obj.MaxLength = &num
+		if *maxLength == nil {
+			*maxLength = s.MaxLength
 		}
 	}
 
-	minItems := directive.Arguments.ForName("minItems")
-	if minItems != nil {
-		num, err := strconv.ParseInt(minItems.Value.String(), 10, 64)
-		if err != nil {
-			log.Printf("parse variable:%v minItems value:%v to int error:%v", variableName, minItems.Value.String(), err.Error())
-		} else {
-			obj.MinItems = &num
-		}
-	}
-
-	maxItems := directive.Arguments.ForName("maxItems")
-	if maxItems != nil {
-		num, err := strconv.ParseInt(maxItems.Value.String(), 10, 64)
-		if err != nil {
-			log.Printf("parse variable:%v maxItems value:%v to int error:%v", variableName, maxItems.Value.String(), err.Error())
-		} else {
-			obj.MaxItems = &num
-		}
-	}
-
-	return obj
+	return schema
 }
@@ -0,0 +1,186 @@
+package restgen
+
+import (
+	"testing"
+
+	validatorConfig "github.com/speedoops/go-gqlrest/config"
+	"github.com/speedoops/go-gqlrest/scalar"
+	"github.com/stretchr/testify/assert"
+	"github.com/vektah/gqlparser/v2"
+	"github.com/vektah/gqlparser/v2/ast"
+	"gopkg.in/yaml.v2"
+)
+
+func TestGenerateAPIResponse(t *testing.T) {
+	m := &DocPlugin{}
+
+	t.Run("default status", func(t *testing.T) {
+		responses := m.generateAPIResponse("UserResponse", 0, nil, nil)
+
+		assert.Len(t, responses, 5)
+		assert.Equal(t, "#/components/schemas/UserResponse", responses["200"].Content.Json.Schema.Ref)
+		for _, status := range []string{"400", "422", "500", "default"} {
+			assert.Equal(t, "#/components/schemas/"+errorResponseObject, responses[status].Content.Json.Schema.Ref)
+		}
+	})
+
+	t.Run("created with declared errors", func(t *testing.T) {
+		responses := m.generateAPIResponse("CreateUserResponse", 201, []int{404, 409}, nil)
+
+		assert.NotContains(t, responses, "200")
+		assert.Equal(t, "Created", responses["201"].Description)
+		assert.Equal(t, "Not Found", responses["404"].Description)
+		assert.Equal(t, "Conflict", responses["409"].Description)
+	})
+
+	t.Run("other media types", func(t *testing.T) {
+		responses := m.generateAPIResponse("UsersResponse", 0, nil, []string{"text/csv", "application/yaml"})
+
+		b, err := yaml.Marshal(responses["200"].Content)
+		assert.NoError(t, err)
+		assert.Equal(t, `application/json:
+  schema:
+    $ref: '#/components/schemas/UsersResponse'
+application/yaml:
+  schema:
+    $ref: '#/components/schemas/UsersResponse'
+text/csv:
+  schema:
+    type: string
+    description: CSV，首行为列名，每个元素一行，嵌套字段的列名为点分路径，如 owner.name
+`, string(b))
+		assert.NotContains(t, responses["400"].Content.Others, "text/csv")
+	})
+
+	t.Run("unstructured media types", func(t *testing.T) {
+		responses := m.generateAPIResponse("UsersResponse", 0, nil, []string{"text/plain", "application/msgpack", "application/vnd.users+json"})
+
+		others := responses["200"].Content.Others
+		assert.Equal(t, &SchemaType{Type: "string"}, others["text/plain"].Schema)
+		assert.Equal(t, "#/components/schemas/UsersResponse", others["application/msgpack"].Schema.Ref)
+		assert.Equal(t, "#/components/schemas/UsersResponse", others["application/vnd.users+json"].Schema.Ref)
+	})
+
+	t.Run("no content", func(t *testing.T) {
+		responses := m.generateAPIResponse("DeleteUserResponse", 204, nil, nil)
+
+		assert.Nil(t, responses["204"].Content)
+	})
+}
+
+func TestStreamResponse(t *testing.T) {
+	m := &DocPlugin{}
+	responses := m.generateAPIResponse("UserExportedResponse", 0, nil, nil)
+	m.setStreamResponse(responses, ast.NonNullNamedType("User", nil))
+
+	b, err := yaml.Marshal(responses["200"].Content)
+	assert.NoError(t, err)
+	assert.Equal(t, `application/x-ndjson:
+  schema:
+    $ref: '#/components/schemas/User'
+`, string(b))
+}
+
+func TestEventStreamResponse(t *testing.T) {
+	m := &DocPlugin{}
+	responses := m.generateAPIResponse("UserAddedResponse", 0, nil, nil)
+	m.setEventStreamResponse(responses, "UserAddedResponse")
+
+	b, err := yaml.Marshal(responses["200"].Content)
+	assert.NoError(t, err)
+	assert.Equal(t, `text/event-stream:
+  schema:
+    $ref: '#/components/schemas/UserAddedResponse'
+`, string(b))
+	assert.Equal(t, "header", m.generateLastEventIDParameter().In)
+}
+
+func TestProblemErrorResponse(t *testing.T) {
+	validatorConfig.SetProblemDetails(true)
+	defer validatorConfig.SetProblemDetails(false)
+
+	m := &DocPlugin{}
+	responses := m.generateAPIResponse("UserResponse", 0, nil, nil)
+	assert.Nil(t, responses["400"].Content.Json)
+	assert.Equal(t, "#/components/schemas/"+errorResponseObject, responses["400"].Content.Problem.Schema.Ref)
+	assert.NotNil(t, responses["200"].Content.Json)
+
+	obj := m.generateErrorResponse()
+	keys := make([]interface{}, 0)
+	for _, item := range obj.Properties {
+		keys = append(keys, item.Key)
+	}
+	assert.Equal(t, []interface{}{"type", "title", "status", "detail", "instance", "codestr", "errors"}, keys)
+	assert.Equal(t, []string{problemErrorObject}, obj.relatedObjects)
+}
+
+func TestScalarTypes(t *testing.T) {
+	scalars := scalar.NewRegistry()
+	scalars.Register(scalar.Scalar{Name: "Duration", Type: scalar.TypeInteger, Format: "int64"})
+	scalars.Register(scalar.Scalar{Name: "UUID", Format: "uuid", Pattern: "^[0-9a-f-]{36}$"})
+
+	schema := gqlparser.MustLoadSchema(&ast.Source{Input: `
+		directive @constraintString(maxLength: Int) on ARGUMENT_DEFINITION
+		scalar Time
+		scalar IP
+		scalar UUID
+		scalar Duration
+		scalar Color
+		scalar Upload
+		type Query {
+			hosts(
+				created: Time
+				ip: IP
+				ids: [UUID!]
+				owner: UUID @constraintString(maxLength: 8)
+				timeout: Duration
+				color: Color
+				file: Upload
+			): Int
+		}
+	`})
+	m := &DocPlugin{schema: schema, scalars: scalars}
+	args := schema.Query.Fields.ForName("hosts").Arguments
+
+	tests := []struct {
+		Arg      string
+		Expected string
+	}{
+		{"created", "type: string\nformat: date-time\n"},
+		{"ip", "type: string\nformat: ip\nmaxLength: 39\n"},
+		{"ids", "type: array\nitems:\n  type: string\n  format: uuid\n  pattern: ^[0-9a-f-]{36}$\n"},
+		{"owner", "type: string\nformat: uuid\nmaxLength: 8\npattern: ^[0-9a-f-]{36}$\n"},
+		{"timeout", "type: integer\nformat: int64\n"},
+		{"color", "type: string\n"},
+		{"file", "$ref: '#/components/schemas/Upload'\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Arg, func(t *testing.T) {
+			arg := args.ForName(tt.Arg)
+			b, err := yaml.Marshal(m.parseType(arg.Name, arg.Type, &arg.Directives))
+			assert.NoError(t, err)
+			assert.Equal(t, tt.Expected, string(b))
+		})
+	}
+}
+
+func TestSortParameter(t *testing.T) {
+	m := &DocPlugin{schema: gqlparser.MustLoadSchema(&ast.Source{Input: `
+		enum Direction { ASC DESC }
+		enum LabelSort { CREATED CREATED_DESC NAME }
+		input GroupSort {
+			name: Direction
+		}
+		type Query { labels: [String!]! }
+	`})}
+
+	param := m.parseSortParameter(ast.ListType(ast.NonNullNamedType("LabelSort", nil), nil), "")
+	assert.Equal(t, "排序字段，多个字段以逗号分隔，字段前加 - 表示降序，如 -created,name\n\n可选字段: CREATED, -CREATED, NAME", param.Description)
+
+	param = m.parseSortParameter(ast.NamedType("LabelSort", nil), "")
+	assert.Equal(t, "排序字段，字段前加 - 表示降序，如 -created\n\n可选字段: CREATED, -CREATED, NAME", param.Description)
+
+	param = m.parseSortParameter(ast.NamedType("GroupSort", nil), "")
+	assert.Equal(t, "排序字段，多个字段以逗号分隔，字段前加 - 表示降序，如 -created,name\n\n可选字段: name, -name", param.Description)
+}
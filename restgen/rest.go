@@ -2,9 +2,15 @@ package restgen
 
 import (
 	_ "embed"
+	"errors"
 	"fmt"
+	"io"
 	"log"
+	"math"
+	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 	"syscall"
 	"text/template"
@@ -13,6 +19,8 @@ import (
 	"github.com/99designs/gqlgen/codegen/config"
 	"github.com/99designs/gqlgen/codegen/templates"
 	"github.com/99designs/gqlgen/plugin"
+	validatorConfig "github.com/speedoops/go-gqlrest/config"
+	"github.com/speedoops/go-gqlrest/constraint"
 	"github.com/speedoops/go-gqlrest/restgen/utils"
 	"github.com/vektah/gqlparser/v2/ast"
 )
@@ -20,6 +28,14 @@ import (
 //go:embed rest.gotpl
 var restTemplate string
 
+// warningOutput receives the warnings about the routes which are not generated as declared, eg. a cut off
+// selection, they are written regardless of -verbose, unlike the log
+var warningOutput io.Writer = os.Stderr
+
+func warnf(format string, v ...interface{}) {
+	fmt.Fprintf(warningOutput, "WARNING: "+format+"\n", v...)
+}
+
 func New(filename string, typename string) plugin.Plugin {
 	return &Plugin{filename: filename, typeName: typename}
 }
@@ -60,15 +76,21 @@ func IsIgnoreField(field *codegen.Field) bool {
 	return false
 }
 
+// GetSelection returns the default REST selection of a field, eg. "{id,text,user{id}}".
+// Object fields whose type is already selected by an ancestor (a cycle), or which are nested
+// deeper than the configured maximum depth or a `@restDepth(max: n)` directive, are cut off,
+// and every cut-off point is reported with a warning.
 func GetSelection(objects *codegen.Objects, field *codegen.Field, refer bool) string {
-	// if !refer {
-	// 	log.Println("\n+++++++++++++++++++++++++++++++++++++++++")
-	// }
-	// log.Println("=> field:", field.Object.Name, field.Name, field.FieldDefinition.Directives)
+	selection, _ := getSelection(objects, field, refer)
+	return selection
+}
 
+// getSelection returns the selection of GetSelection, or an error if the type of the field is an object,
+// interface or union and none of its fields can be selected, eg. an interface without implementations
+func getSelection(objects *codegen.Objects, field *codegen.Field, refer bool) (string, error) {
 	// 忽略内置字段
 	if IsIgnoreField(field) {
-		return ""
+		return "", nil
 	}
 
 	selection := ""
@@ -76,39 +98,350 @@ func GetSelection(objects *codegen.Objects, field *codegen.Field, refer bool) st
 		selection = field.Name
 	}
 
+	limit := validatorConfig.GetMaxSelectionDepth()
+	if limit <= 0 {
+		limit = math.MaxInt32
+	}
+	if max, ok := GetRestDepth(field.FieldDefinition); ok && max < limit {
+		limit = max
+	}
+
+	walker := &selectionWalker{objects: objects}
+	def := field.TypeReference.Definition
+	if def.IsAbstractType() && len(walker.possibleTypes(def)) == 0 {
+		return "", fmt.Errorf("REST selection of '%s.%s' is empty: %s '%s' has no implementations",
+			field.Object.Name, field.Name, strings.ToLower(string(def.Kind)), def.Name)
+	}
+	innerSelection := walker.walk(def, field.Name, []string{def.Name}, 1, limit)
+	for _, cutoff := range walker.cutoffs {
+		warnf("REST selection of '%s.%s' cut off at %s.", field.Object.Name, field.Name, cutoff)
+	}
+	if innerSelection == "" && (def.Kind == ast.Object || def.IsAbstractType()) {
+		return "", fmt.Errorf("REST selection of '%s.%s' is empty: no field of '%s' can be selected", field.Object.Name, field.Name, def.Name)
+	}
+
+	return selection + innerSelection, nil
+}
+
+// selectionWalker builds the selection of an object type, and records where it was cut off
+type selectionWalker struct {
+	objects *codegen.Objects
+	cutoffs []string
+}
+
+// walk returns the selection of the fields of def, which are at nesting level depth.
+// Fields nested deeper than limit are not selected. Interfaces and unions select
+// `__typename` and an inline fragment for each possible object type.
+func (w *selectionWalker) walk(def *ast.Definition, path string, visited []string, depth int, limit int) string {
+	innerSelections := w.walkFields(def, nil, path, visited, depth, limit)
+
+	if def.IsAbstractType() {
+		for _, possibleType := range w.possibleTypes(def) {
+			fragmentVisited := append(visited, possibleType.Name)
+			fragmentSelections := w.walkFields(possibleType.Definition, def, path, fragmentVisited, depth, limit)
+			if len(fragmentSelections) > 0 {
+				innerSelections = append(innerSelections, "... on "+possibleType.Name+"{"+strings.Join(fragmentSelections, ",")+"}")
+			}
+		}
+		if len(innerSelections) > 0 {
+			innerSelections = append([]string{"__typename"}, innerSelections...)
+		}
+	}
+
+	if len(innerSelections) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(innerSelections, ",") + "}"
+}
+
+// walkFields returns the selections of the fields of def, except those already selected on the interface iface
+func (w *selectionWalker) walkFields(def *ast.Definition, iface *ast.Definition, path string, visited []string, depth int, limit int) []string {
 	innerSelections := make([]string, 0)
-	for _, innerField := range field.TypeReference.Definition.Fields {
-		// log.Println("..innerField:", innerField.Name, innerField.Type)
-		innerDirective := innerField.Directives.ForName("hide")
-		// if innerDirective != nil {
-		// 	log.Println("..innerField.directive:", innerDirective.Name, ShouldHide(innerDirective))
-		// }
-		if ShouldHide(innerDirective) {
+	for _, innerField := range def.Fields {
+		// 忽略内置字段和未选字段
+		if strings.HasPrefix(innerField.Name, "__") || ShouldHide(innerField.Directives.ForName("hide")) {
+			continue
+		}
+		if iface != nil && iface.Fields.ForName(innerField.Name) != nil {
+			continue
+		}
+
+		innerPath := path + "." + innerField.Name
+		arguments, unbound := GetRestArguments(innerField)
+		if len(unbound) > 0 {
+			w.cutoffs = append(w.cutoffs, fmt.Sprintf("'%s' (required arguments '%s' not bound by @restArg)",
+				innerPath, strings.Join(unbound, "', '")))
 			continue
 		}
 
 		innerFieldTypeName := strings.ReplaceAll(innerField.Type.Name(), "!", "")
-		referObject := objects.ByName(innerFieldTypeName)
-		if referObject == nil {
-			innerSelections = append(innerSelections, innerField.Name)
+		referDefinition := w.definitionByName(innerFieldTypeName)
+		switch {
+		case referDefinition == nil:
+			w.cutoffs = append(w.cutoffs, fmt.Sprintf("'%s' (unknown type '%s')", innerPath, innerFieldTypeName))
+			continue
+		case referDefinition.Kind == ast.Scalar || referDefinition.Kind == ast.Enum:
+			innerSelections = append(innerSelections, innerField.Name+arguments)
+			continue
+		case referDefinition.IsAbstractType() && len(w.possibleTypes(referDefinition)) == 0:
+			w.cutoffs = append(w.cutoffs, fmt.Sprintf("'%s' (%s '%s' has no implementations)",
+				innerPath, strings.ToLower(string(referDefinition.Kind)), innerFieldTypeName))
+			continue
+		}
+
+		if contains(visited, innerFieldTypeName) {
+			w.cutoffs = append(w.cutoffs, fmt.Sprintf("'%s' (cyclic type '%s')", innerPath, innerFieldTypeName))
 			continue
 		}
 
-		referSelections := make([]string, 0)
-		for _, referField := range referObject.Fields {
-			xxx := GetSelection(objects, referField, true)
-			if xxx != "" {
-				referSelections = append(referSelections, xxx)
+		innerLimit, reason := limit, fmt.Sprintf("max depth %d", limit)
+		if max, ok := GetRestDepth(innerField); ok && depth+max < innerLimit {
+			innerLimit, reason = depth+max, fmt.Sprintf("@restDepth(max: %d)", max)
+		}
+		if depth+1 > innerLimit {
+			w.cutoffs = append(w.cutoffs, fmt.Sprintf("'%s' (%s)", innerPath, reason))
+			continue
+		}
+
+		referSelection := w.walk(referDefinition, innerPath, append(visited, innerFieldTypeName), depth+1, innerLimit)
+		if referSelection != "" {
+			innerSelections = append(innerSelections, innerField.Name+arguments+referSelection)
+		}
+	}
+	return innerSelections
+}
+
+// definitionByName returns the definition of a type, read from the objects, the interfaces and unions they
+// implement and the types of their fields, or nil if it is not known
+func (w *selectionWalker) definitionByName(name string) *ast.Definition {
+	if object := w.objects.ByName(name); object != nil {
+		return object.Definition
+	}
+	for _, object := range *w.objects {
+		for _, implement := range object.Implements {
+			if implement.Name == name {
+				return implement
+			}
+		}
+		for _, field := range object.Fields {
+			if field.TypeReference != nil && field.TypeReference.Definition != nil && field.TypeReference.Definition.Name == name {
+				return field.TypeReference.Definition
+			}
+		}
+	}
+	return nil
+}
+
+// possibleTypes returns the objects implementing the interface, or the members of the union def
+func (w *selectionWalker) possibleTypes(def *ast.Definition) []*codegen.Object {
+	possibleTypes := make([]*codegen.Object, 0)
+	for _, object := range *w.objects {
+		for _, implement := range object.Implements {
+			if implement.Name == def.Name {
+				possibleTypes = append(possibleTypes, object)
+				break
 			}
 		}
-		if len(referSelections) > 0 {
-			innerSelections = append(innerSelections, innerField.Name+"{"+strings.Join(referSelections, ",")+"}")
+	}
+	sort.Slice(possibleTypes, func(i, j int) bool {
+		return possibleTypes[i].Name < possibleTypes[j].Name
+	})
+	return possibleTypes
+}
+
+// GetRestArguments returns the literal arguments to select a nested field with, eg. `(window:"5m")`.
+// Arguments are bound from the `@restArg(window: "5m")` directive, checked by CheckRestArguments,
+// and required arguments without a default value that are not bound are returned as unbound.
+func GetRestArguments(field *ast.FieldDefinition) (string, []string) {
+	directive := field.Directives.ForName("restArg")
+
+	arguments := make([]string, 0)
+	unbound := make([]string, 0)
+	for _, arg := range field.Arguments {
+		if directive != nil {
+			if value := directive.Arguments.ForName(arg.Name); value != nil {
+				arguments = append(arguments, arg.Name+":"+value.Value.String())
+				continue
+			}
+		}
+		if arg.Type.NonNull && arg.DefaultValue == nil {
+			unbound = append(unbound, arg.Name)
 		}
 	}
-	if len(innerSelections) > 0 {
-		selection += "{" + strings.Join(innerSelections, ",") + "}"
+
+	if len(arguments) == 0 {
+		return "", unbound
 	}
-	return selection
+	return "(" + strings.Join(arguments, ",") + ")", unbound
+}
+
+// CheckRestArguments checks the `@restArg` directives of the schema: each value must be a literal
+// of the type of a field argument, as it is spliced into the REST selections as is.
+func CheckRestArguments(schema *ast.Schema) error {
+	names := make([]string, 0, len(schema.Types))
+	for name := range schema.Types {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		for _, field := range schema.Types[name].Fields {
+			directive := field.Directives.ForName("restArg")
+			if directive == nil {
+				continue
+			}
+			for _, value := range directive.Arguments {
+				arg := field.Arguments.ForName(value.Name)
+				if arg == nil {
+					return fmt.Errorf("@restArg of %s.%s: unknown argument '%s'", name, field.Name, value.Name)
+				}
+				if err := checkRestArgValue(schema, arg.Type, value.Value); err != nil {
+					return fmt.Errorf("@restArg of %s.%s: argument '%s' of type %s: %w", name, field.Name, value.Name, arg.Type.String(), err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// CheckStreams checks that only subscriptions are streamed: a query list is resolved and marshaled
+// as a whole, its elements can not be written as they are resolved.
+func CheckStreams(objects codegen.Objects) error {
+	for _, object := range objects {
+		for _, field := range object.Fields {
+			if IsStreamed(field) && !IsSubscription(field) {
+				return fmt.Errorf("@http of %s.%s: only subscriptions can be streamed", object.Name, field.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// checkRestArgValue checks that a literal value is of a type, as the input coercion of GraphQL
+func checkRestArgValue(schema *ast.Schema, typ *ast.Type, value *ast.Value) error {
+	if value == nil || value.Kind == ast.NullValue {
+		if typ.NonNull {
+			return errors.New("null is not allowed")
+		}
+		return nil
+	}
+	if value.Kind == ast.Variable {
+		return fmt.Errorf("variables are not allowed, found %s", value.String())
+	}
+
+	if typ.Elem != nil {
+		if value.Kind != ast.ListValue {
+			// a single value is coerced into a list of one
+			return checkRestArgValue(schema, typ.Elem, value)
+		}
+		for _, item := range value.Children {
+			if err := checkRestArgValue(schema, typ.Elem, item.Value); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	unexpected := fmt.Errorf("unexpected value %s", value.String())
+	switch typ.NamedType {
+	case "Int":
+		if value.Kind != ast.IntValue {
+			return unexpected
+		}
+		if _, err := strconv.ParseInt(value.Raw, 10, 32); err != nil {
+			return fmt.Errorf("%s is out of range", value.Raw)
+		}
+		return nil
+	case "Float":
+		if value.Kind != ast.IntValue && value.Kind != ast.FloatValue {
+			return unexpected
+		}
+		return nil
+	case "String":
+		if value.Kind != ast.StringValue && value.Kind != ast.BlockValue {
+			return unexpected
+		}
+		return nil
+	case "Boolean":
+		if value.Kind != ast.BooleanValue {
+			return unexpected
+		}
+		return nil
+	case "ID":
+		if value.Kind != ast.StringValue && value.Kind != ast.IntValue {
+			return unexpected
+		}
+		return nil
+	}
+
+	def := schema.Types[typ.NamedType]
+	if def == nil {
+		return fmt.Errorf("unknown type %s", typ.NamedType)
+	}
+	switch def.Kind {
+	case ast.Enum:
+		if value.Kind != ast.EnumValue || def.EnumValues.ForName(value.Raw) == nil {
+			return unexpected
+		}
+	case ast.InputObject:
+		if value.Kind != ast.ObjectValue {
+			return unexpected
+		}
+		for _, child := range value.Children {
+			if def.Fields.ForName(child.Name) == nil {
+				return fmt.Errorf("unknown field '%s' of %s", child.Name, def.Name)
+			}
+		}
+		for _, field := range def.Fields {
+			child := value.Children.ForName(field.Name)
+			if child == nil {
+				if field.Type.NonNull && field.DefaultValue == nil {
+					return fmt.Errorf("missing field '%s' of %s", field.Name, def.Name)
+				}
+				continue
+			}
+			if err := checkRestArgValue(schema, field.Type, child); err != nil {
+				return err
+			}
+		}
+	case ast.Scalar:
+		// custom scalars take any literal, but objects and lists
+		if value.Kind == ast.ObjectValue || value.Kind == ast.ListValue {
+			return unexpected
+		}
+	}
+	return nil
+}
+
+// GetRestDepth reads the `@restDepth(max: n)` directive, which limits how many levels
+// of nested objects are selected below a field.
+func GetRestDepth(field *ast.FieldDefinition) (int, bool) {
+	directive := field.Directives.ForName("restDepth")
+	if directive == nil {
+		return 0, false
+	}
+
+	maxName := directive.Arguments.ForName("max")
+	if maxName == nil {
+		return 0, false
+	}
+
+	max, err := strconv.Atoi(maxName.Value.Raw)
+	if err != nil || max < 0 {
+		log.Printf("WARNING: field '%s' has invalid @restDepth(max: %s).\n", field.Name, maxName.Value.String())
+		return 0, false
+	}
+
+	return max, true
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
 }
 
 // _$_ [Using Functions Inside Go Templates - Calhoun.io](https://www.calhoun.io/intro-to-templates-p3-functions/# ) | ClippedOn=2021-08-10T09:45:06.709Z
@@ -155,6 +488,138 @@ func GetMethod(field *codegen.Field, defaultMethod string) string {
 	return methodValue
 }
 
+// getHTTPArgument returns the raw value of an argument of the @http directive, or "" if not set
+func getHTTPArgument(field *codegen.Field, name string) string {
+	directive := field.FieldDefinition.Directives.ForName("http")
+	if directive == nil {
+		return ""
+	}
+
+	arg := directive.Arguments.ForName(name)
+	if arg == nil || arg.Value == nil {
+		return ""
+	}
+
+	return arg.Value.Raw
+}
+
+// IsPaginated reports whether a list route accepts the standard paging parameters, eg. `@http(url: "/todos", paginate: true)`
+func IsPaginated(field *codegen.Field) bool {
+	return getHTTPArgument(field, "paginate") == "true"
+}
+
+// IsStreamed reports whether a subscription route writes its payloads as NDJSON, one line each, instead of
+// server-sent events, eg. `@http(url: "/todos/export", stream: true)`
+func IsStreamed(field *codegen.Field) bool {
+	return getHTTPArgument(field, "stream") == "true"
+}
+
+// IsIdempotent reports whether a mutation route honours the Idempotency-Key header, eg. `@http(url: "/todos", idempotent: true)`
+func IsIdempotent(field *codegen.Field) bool {
+	return getHTTPArgument(field, "idempotent") == "true"
+}
+
+// IsStrict reports whether a route rejects the parameters its operation does not take, eg. `@http(url: "/todos", strict: true)`
+func IsStrict(field *codegen.Field) bool {
+	return getHTTPArgument(field, "strict") == "true"
+}
+
+// IsSubscription reports whether a route is a field of the Subscription type, its payloads are
+// streamed as server-sent events, or as NDJSON if IsStreamed
+func IsSubscription(field *codegen.Field) bool {
+	return field.Object != nil && field.Object.Stream
+}
+
+// GetStatus returns the HTTP status of the successful responses of a route, eg. `@http(url: "/todos", status: 201)`,
+// or 0 if not set
+func GetStatus(field *codegen.Field) int {
+	value := getHTTPArgument(field, "status")
+	if value == "" {
+		return 0
+	}
+
+	status, err := strconv.Atoi(value)
+	if err != nil || status < 200 || status > 299 {
+		warnf("@http status of '%s.%s' is not a 2xx status: %s", field.Object.Name, field.Name, value)
+		return 0
+	}
+	return status
+}
+
+// getHTTPListArgument returns the raw values of a list argument of the @http directive, or nil if not set
+func getHTTPListArgument(field *codegen.Field, name string) []string {
+	directive := field.FieldDefinition.Directives.ForName("http")
+	if directive == nil {
+		return nil
+	}
+	arg := directive.Arguments.ForName(name)
+	if arg == nil || arg.Value == nil {
+		return nil
+	}
+
+	values := make([]string, 0, len(arg.Value.Children))
+	for _, child := range arg.Value.Children {
+		values = append(values, child.Value.Raw)
+	}
+	return values
+}
+
+// GetErrorStatuses returns the HTTP statuses of the errors of a route, eg. `@http(url: "/todos/{id}", errors: [404, 409])`
+func GetErrorStatuses(field *codegen.Field) []int {
+	values := getHTTPListArgument(field, "errors")
+	if values == nil {
+		return nil
+	}
+
+	statuses := make([]int, 0, len(values))
+	for _, value := range values {
+		status, err := strconv.Atoi(value)
+		if err != nil || status < 400 || status > 599 {
+			warnf("@http errors of '%s.%s' has a status other than 4xx or 5xx: %s", field.Object.Name, field.Name, value)
+			continue
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+// GetProduces returns the media types of the responses of a route besides JSON, eg. `@http(url: "/todos", produces: ["text/csv"])`
+func GetProduces(field *codegen.Field) []string {
+	return getHTTPListArgument(field, "produces")
+}
+
+// GetRouteOptions returns the REST options of a route as a Go composite literal for the generated code
+func GetRouteOptions(field *codegen.Field) string {
+	options := make([]string, 0)
+	if IsPaginated(field) {
+		options = append(options, "Paginate: true")
+	}
+	if IsStreamed(field) {
+		options = append(options, "Stream: true")
+	}
+	if IsSubscription(field) {
+		options = append(options, "Subscription: true")
+	}
+	if IsIdempotent(field) {
+		options = append(options, "Idempotent: true")
+	}
+	if IsStrict(field) {
+		options = append(options, "StrictParams: true")
+	}
+	if status := GetStatus(field); status != 0 {
+		options = append(options, fmt.Sprintf("Status: %d", status))
+	}
+	if produces := GetProduces(field); len(produces) > 0 {
+		mediaTypes := make([]string, 0, len(produces))
+		for _, mediaType := range produces {
+			mediaTypes = append(mediaTypes, strconv.Quote(mediaType))
+		}
+		options = append(options, "Produces: []string{"+strings.Join(mediaTypes, ", ")+"}")
+	}
+
+	return "&handlerx.RouteOptions{" + strings.Join(options, ", ") + "}"
+}
+
 func StaticCheck(data *codegen.Data) {
 	for _, object := range data.MutationRoot.Fields {
 		for _, field := range object.Arguments {
@@ -189,8 +654,50 @@ func StaticCheck(data *codegen.Data) {
 	}
 }
 
+// GetConstraintFormats returns the Go literal of the formats, for the ConstraintFormats of the generated code
+func GetConstraintFormats(formats constraint.Formats) string {
+	names := make([]string, 0, len(formats))
+	for name := range formats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var sb strings.Builder
+	sb.WriteString("constraint.Formats{")
+	for _, name := range names {
+		format := formats[name]
+		fmt.Fprintf(&sb, "\n\t%s: {Pattern: %s", strconv.Quote(name), strconv.Quote(format.Pattern))
+		if format.MinLength != 0 {
+			fmt.Fprintf(&sb, ", MinLength: %d", format.MinLength)
+		}
+		if format.MaxLength != 0 {
+			fmt.Fprintf(&sb, ", MaxLength: %d", format.MaxLength)
+		}
+		sb.WriteString("},")
+	}
+	if len(names) > 0 {
+		sb.WriteString("\n")
+	}
+	sb.WriteString("}")
+	return sb.String()
+}
+
+// GetErrorFormat returns the Go constant of the error format the code is generated with, see -problem
+func GetErrorFormat() string {
+	if validatorConfig.GetProblemDetails() {
+		return "handlerx.ProblemErrorFormat"
+	}
+	return "handlerx.EnvelopeErrorFormat"
+}
+
 func (m *Plugin) GenerateCode(data *codegen.Data) error {
 	StaticCheck(data)
+	if err := CheckRestArguments(data.Schema); err != nil {
+		return err
+	}
+	if err := CheckStreams(data.Objects); err != nil {
+		return err
+	}
 
 	abs, err := filepath.Abs(m.filename)
 	if err != nil {
@@ -207,8 +714,8 @@ func (m *Plugin) GenerateCode(data *codegen.Data) error {
 			TypeName: m.typeName,
 		},
 		Funcs: template.FuncMap{
-			"getSelection": func(objects *codegen.Objects, field *codegen.Field, refer bool) string {
-				return GetSelection(objects, field, refer)
+			"getSelection": func(objects *codegen.Objects, field *codegen.Field, refer bool) (string, error) {
+				return getSelection(objects, field, refer)
 			},
 			"getURL": func(field *codegen.Field) string {
 				return GetURL(field)
@@ -216,6 +723,15 @@ func (m *Plugin) GenerateCode(data *codegen.Data) error {
 			"getMethod": func(field *codegen.Field, defaultMethod string) string {
 				return GetMethod(field, defaultMethod)
 			},
+			"getRouteOptions": func(field *codegen.Field) string {
+				return GetRouteOptions(field)
+			},
+			"constraintFormats": func() string {
+				return GetConstraintFormats(constraint.FormatsOf(validatorConfig.GetValidators()))
+			},
+			"errorFormat": func() string {
+				return GetErrorFormat()
+			},
 		},
 		GeneratedHeader: true,
 		Packages:        data.Config.Packages,
@@ -0,0 +1,359 @@
+package restgen
+
+import (
+	"bytes"
+	goparser "go/parser"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/99designs/gqlgen/codegen"
+	"github.com/99designs/gqlgen/codegen/config"
+	validatorConfig "github.com/speedoops/go-gqlrest/config"
+	"github.com/speedoops/go-gqlrest/constraint"
+	"github.com/stretchr/testify/assert"
+	"github.com/vektah/gqlparser/v2"
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+var testSchema = gqlparser.MustLoadSchema(&ast.Source{Input: `
+	directive @hide(for: [String!]) on FIELD_DEFINITION
+	directive @restDepth(max: Int!) on FIELD_DEFINITION
+	directive @restArg on FIELD_DEFINITION
+	directive @http(url: String!, method: String, paginate: Boolean, status: Int, errors: [Int!], produces: [String!], stream: Boolean, idempotent: Boolean, strict: Boolean) on FIELD_DEFINITION
+
+	type User {
+		id: ID!
+		name: String!
+		password: String! @hide(for: ["rest"])
+		manager: User
+		group: Group
+	}
+	type Group {
+		id: ID!
+		owner: User
+		parent: Group
+		labels: [Label!] @restDepth(max: 0)
+	}
+	type Label {
+		key: String!
+	}
+	type Host {
+		id: ID!
+		cluster: Cluster!
+		metrics(window: String!, step: Int = 10): Metrics @restArg(window: "5m")
+		usage(window: String!): Float
+		load(window: String): Float
+	}
+	type Metrics {
+		avg: Float!
+	}
+	type Cluster {
+		id: ID!
+		hosts: [Host!]!
+	}
+	interface Resource {
+		id: ID!
+		name: String!
+	}
+	type Vm implements Resource {
+		id: ID!
+		name: String!
+		vcpu: Int!
+		host: Host
+		orphan: Orphan
+	}
+	type Volume implements Resource {
+		id: ID!
+		name: String!
+		size: Int!
+	}
+	union SearchResult = Vm | User
+	interface Orphan {
+		id: ID!
+	}
+	type Query {
+		resources: [Resource!]!
+		search(q: String!): [SearchResult!]!
+		user(id: ID!): User @http(url: "/users/{id}", errors: [404, 200])
+		users: [User!]! @http(url: "/users", paginate: true, produces: ["text/csv", "application/yaml"])
+		createUser(name: String!): User! @http(url: "/users", method: "POST", status: 201, errors: [409], idempotent: true)
+		deleteUser(id: ID!): Boolean! @http(url: "/users/{id}", method: "DELETE", status: 204)
+		renameUser(id: ID!): Boolean! @http(url: "/users/{id}", method: "PUT", status: 404, strict: true)
+		group(id: ID!): Group @restDepth(max: 1)
+		hosts: [Host!]! @http(url: "/hosts")
+		orphans: [Orphan!]!
+	}
+	type Subscription {
+		userAdded: User! @http(url: "/users/events")
+		userExported: User! @http(url: "/users/export", stream: true)
+	}
+`})
+
+// newTestObjects builds just enough of codegen.Objects for GetSelection
+func newTestObjects() *codegen.Objects {
+	objects := codegen.Objects{}
+	for _, def := range testSchema.Types {
+		if def.Kind == ast.Object && !def.BuiltIn {
+			object := &codegen.Object{Definition: def, Implements: testSchema.GetImplements(def)}
+			for _, fieldDef := range def.Fields {
+				object.Fields = append(object.Fields, &codegen.Field{
+					FieldDefinition: fieldDef,
+					Object:          object,
+					TypeReference:   &config.TypeReference{Definition: testSchema.Types[fieldDef.Type.Name()]},
+				})
+			}
+			objects = append(objects, object)
+		}
+	}
+	return &objects
+}
+
+func newTestField(name string) *codegen.Field {
+	fieldDef := testSchema.Query.Fields.ForName(name)
+	return &codegen.Field{
+		FieldDefinition: fieldDef,
+		Object:          &codegen.Object{Definition: testSchema.Query},
+		TypeReference:   &config.TypeReference{Definition: testSchema.Types[fieldDef.Type.Name()]},
+	}
+}
+
+func TestGetSelection(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Field    string
+		MaxDepth int
+		Expected string
+	}{
+		{
+			Name:     "cycles are cut off",
+			Field:    "user",
+			Expected: "{id,name,group{id}}",
+		},
+		{
+			Name:     "cycles through lists are cut off",
+			Field:    "hosts",
+			Expected: `{id,cluster{id},metrics(window:"5m"){avg},load}`,
+		},
+		{
+			Name:     "global max depth",
+			Field:    "user",
+			MaxDepth: 1,
+			Expected: "{id,name}",
+		},
+		{
+			Name:     "restDepth directive",
+			Field:    "group",
+			Expected: "{id}",
+		},
+		{
+			Name:     "interface",
+			Field:    "resources",
+			Expected: `{__typename,id,name,... on Vm{vcpu,host{id,cluster{id},metrics(window:"5m"){avg},load}},... on Volume{size}}`,
+		},
+		{
+			Name:     "union",
+			Field:    "search",
+			Expected: `{__typename,... on User{id,name,group{id}},... on Vm{id,name,vcpu,host{id,cluster{id},metrics(window:"5m"){avg},load}}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			validatorConfig.SetMaxSelectionDepth(tt.MaxDepth)
+			defer validatorConfig.SetMaxSelectionDepth(0)
+
+			assert.Equal(t, tt.Expected, GetSelection(newTestObjects(), newTestField(tt.Field), false))
+		})
+	}
+}
+
+func TestGetSelectionWithoutImplementations(t *testing.T) {
+	// the fields of an interface without implementations are skipped
+	assert.NotContains(t, GetSelection(newTestObjects(), newTestField("resources"), false), "orphan")
+
+	// and the routes returning one can not be generated
+	selection, err := getSelection(newTestObjects(), newTestField("orphans"), false)
+	assert.Empty(t, selection)
+	assert.EqualError(t, err, "REST selection of 'Query.orphans' is empty: interface 'Orphan' has no implementations")
+
+	// the types which are not known are skipped too
+	vm := &codegen.Object{Definition: testSchema.Types["Vm"]}
+	for _, fieldDef := range vm.Definition.Fields {
+		field := &codegen.Field{FieldDefinition: fieldDef, Object: vm}
+		if def := testSchema.Types[fieldDef.Type.Name()]; def.Kind == ast.Scalar {
+			field.TypeReference = &config.TypeReference{Definition: def}
+		}
+		vm.Fields = append(vm.Fields, field)
+	}
+	field := newTestField("search")
+	field.TypeReference = &config.TypeReference{Definition: vm.Definition}
+	selection, err = getSelection(&codegen.Objects{vm}, field, false)
+	assert.NoError(t, err)
+	assert.Equal(t, "{id,name,vcpu}", selection)
+}
+
+func TestSubscriptionRouteOptions(t *testing.T) {
+	fieldDef := testSchema.Subscription.Fields.ForName("userAdded")
+	field := &codegen.Field{
+		FieldDefinition: fieldDef,
+		Object:          &codegen.Object{Definition: testSchema.Subscription, Stream: true},
+	}
+
+	assert.True(t, IsSubscription(field))
+	assert.False(t, IsSubscription(newTestField("user")))
+	assert.Equal(t, "&handlerx.RouteOptions{Subscription: true}", GetRouteOptions(field))
+
+	field = &codegen.Field{
+		FieldDefinition: testSchema.Subscription.Fields.ForName("userExported"),
+		Object:          field.Object,
+	}
+	assert.Equal(t, "&handlerx.RouteOptions{Stream: true, Subscription: true}", GetRouteOptions(field))
+}
+
+func TestCheckStreams(t *testing.T) {
+	subscription := &codegen.Object{Definition: testSchema.Subscription, Stream: true}
+	subscription.Fields = []*codegen.Field{{
+		FieldDefinition: testSchema.Subscription.Fields.ForName("userExported"),
+		Object:          subscription,
+	}}
+	assert.NoError(t, CheckStreams(codegen.Objects{subscription}))
+
+	// query lists are resolved as a whole
+	query := &codegen.Object{Definition: gqlparser.MustLoadSchema(&ast.Source{Input: `
+		directive @http(url: String!, stream: Boolean) on FIELD_DEFINITION
+		type Query { hosts: [String!]! @http(url: "/hosts", stream: true) }
+	`}).Query}
+	query.Fields = []*codegen.Field{{FieldDefinition: query.Definition.Fields.ForName("hosts"), Object: query}}
+	assert.EqualError(t, CheckStreams(codegen.Objects{query}), "@http of Query.hosts: only subscriptions can be streamed")
+}
+
+func TestWarnings(t *testing.T) {
+	// the warnings are written even if the log is discarded, see -verbose
+	var warnings bytes.Buffer
+	warningOutput = &warnings
+	defer func() { warningOutput = os.Stderr }()
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	GetSelection(newTestObjects(), newTestField("user"), false)
+	GetStatus(newTestField("renameUser"))
+	GetErrorStatuses(newTestField("user"))
+	assert.Equal(t, "WARNING: REST selection of 'Query.user' cut off at 'user.manager' (cyclic type 'User').\n"+
+		"WARNING: REST selection of 'Query.user' cut off at 'user.group.owner' (cyclic type 'User').\n"+
+		"WARNING: REST selection of 'Query.user' cut off at 'user.group.parent' (cyclic type 'Group').\n"+
+		"WARNING: REST selection of 'Query.user' cut off at 'user.group.labels' (@restDepth(max: 0)).\n"+
+		"WARNING: @http status of 'Query.renameUser' is not a 2xx status: 404\n"+
+		"WARNING: @http errors of 'Query.user' has a status other than 4xx or 5xx: 200\n", warnings.String())
+}
+
+func TestGetRouteOptions(t *testing.T) {
+	tests := []struct {
+		Field            string
+		Expected         string
+		ExpectedStatus   int
+		ExpectedStatuses []int
+	}{
+		{Field: "user", Expected: "&handlerx.RouteOptions{}", ExpectedStatuses: []int{404}},
+		{Field: "users", Expected: `&handlerx.RouteOptions{Paginate: true, Produces: []string{"text/csv", "application/yaml"}}`, ExpectedStatuses: nil},
+		{Field: "createUser", Expected: "&handlerx.RouteOptions{Idempotent: true, Status: 201}", ExpectedStatus: 201, ExpectedStatuses: []int{409}},
+		{Field: "deleteUser", Expected: "&handlerx.RouteOptions{Status: 204}", ExpectedStatus: 204, ExpectedStatuses: nil},
+		{Field: "renameUser", Expected: "&handlerx.RouteOptions{StrictParams: true}", ExpectedStatuses: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Field, func(t *testing.T) {
+			field := newTestField(tt.Field)
+			assert.Equal(t, tt.Expected, GetRouteOptions(field))
+			assert.Equal(t, tt.ExpectedStatus, GetStatus(field))
+			assert.Equal(t, tt.ExpectedStatuses, GetErrorStatuses(field))
+		})
+	}
+}
+
+func TestRegisterHandlersTemplate(t *testing.T) {
+	// the options of RegisterHandlers, eg. the encoders of the produced media types, are applied before Prepare
+	assert.Contains(t, restTemplate, "opts ...handlerx.MappingOption) *handlerx.Mapping {")
+	newMapping := strings.Index(restTemplate, "handlerx.NewMapping(")
+	prepare := strings.Index(restTemplate, "mapping.Prepare(parsedSchema)")
+	assert.True(t, newMapping >= 0 && newMapping < prepare)
+}
+
+func TestGetErrorFormat(t *testing.T) {
+	assert.Equal(t, "handlerx.EnvelopeErrorFormat", GetErrorFormat())
+
+	validatorConfig.SetProblemDetails(true)
+	defer validatorConfig.SetProblemDetails(false)
+	assert.Equal(t, "handlerx.ProblemErrorFormat", GetErrorFormat())
+
+	// the error format of the generated code is the one of rest.yaml, the options of RegisterHandlers may override it
+	assert.Contains(t, restTemplate, "const ErrorFormat = {{ errorFormat }}")
+	assert.Contains(t, restTemplate, "handlerx.NewMapping(append([]handlerx.MappingOption{handlerx.WithErrorFormat(ErrorFormat)}, opts...)...)")
+}
+
+func TestGetConstraintFormats(t *testing.T) {
+	assert.Equal(t, "constraint.Formats{}", GetConstraintFormats(nil))
+
+	formats := constraint.Formats{
+		"hostname": {Pattern: `^[a-z0-9.-]+$`, MaxLength: 253},
+		"email":    {Pattern: `^\S+@\S+$`},
+	}
+	assert.Equal(t, "constraint.Formats{\n"+
+		"\t\"email\": {Pattern: \"^\\\\S+@\\\\S+$\"},\n"+
+		"\t\"hostname\": {Pattern: \"^[a-z0-9.-]+$\", MaxLength: 253},\n"+
+		"}", GetConstraintFormats(formats))
+
+	_, err := goparser.ParseExpr(GetConstraintFormats(formats))
+	assert.NoError(t, err)
+	assert.Contains(t, restTemplate, "var ConstraintFormats = {{ constraintFormats }}")
+}
+
+func TestCheckRestArguments(t *testing.T) {
+	assert.NoError(t, CheckRestArguments(testSchema))
+
+	tests := []struct {
+		Field    string
+		Expected string
+	}{
+		{`metrics(window: String!): Float @restArg(window: "5m")`, ""},
+		{`metrics(window: [String!]): Float @restArg(window: "5m")`, ""},
+		{`metrics(step: Int, ratio: Float): Float @restArg(step: 10, ratio: 10)`, ""},
+		{`metrics(unit: Unit, range: Range): Float @restArg(unit: SECOND, range: {from: 1})`, ""},
+		{`metrics(window: String!): Float @restArg(window: 5)`,
+			"@restArg of Host.metrics: argument 'window' of type String!: unexpected value 5"},
+		{`metrics(window: String!): Float @restArg(window: null)`,
+			"@restArg of Host.metrics: argument 'window' of type String!: null is not allowed"},
+		{`metrics(step: Int): Float @restArg(step: 99999999999)`,
+			"@restArg of Host.metrics: argument 'step' of type Int: 99999999999 is out of range"},
+		{`metrics(steps: [Int!]): Float @restArg(steps: [1, "2"])`,
+			"@restArg of Host.metrics: argument 'steps' of type [Int!]: unexpected value \"2\""},
+		{`metrics(unit: Unit): Float @restArg(unit: HOUR)`,
+			"@restArg of Host.metrics: argument 'unit' of type Unit: unexpected value HOUR"},
+		{`metrics(range: Range): Float @restArg(range: {to: 1})`,
+			"@restArg of Host.metrics: argument 'range' of type Range: unknown field 'to' of Range"},
+		{`metrics(range: Range): Float @restArg(range: {})`,
+			"@restArg of Host.metrics: argument 'range' of type Range: missing field 'from' of Range"},
+		{`metrics(window: String): Float @restArg(windows: "5m")`,
+			"@restArg of Host.metrics: unknown argument 'windows'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Field, func(t *testing.T) {
+			schema := gqlparser.MustLoadSchema(&ast.Source{Input: `
+				directive @restArg on FIELD_DEFINITION
+				enum Unit { SECOND MINUTE }
+				input Range { from: Int! }
+				type Host { ` + tt.Field + ` }
+				type Query { host: Host }
+			`})
+			err := CheckRestArguments(schema)
+			if tt.Expected == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.EqualError(t, err, tt.Expected)
+		})
+	}
+}
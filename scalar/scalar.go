@@ -0,0 +1,255 @@
+// Package scalar defines the GraphQL scalars of the REST routes: how their REST values are converted
+// into GraphQL variables by handlerx, and how they are typed in the OpenAPI documents by restgen.
+//
+// Each handlerx.Mapping and restgen doc plugin has its own Registry, with the built-in scalars, Time,
+// IP, IPRange and MAC registered. The maximum lengths of IP (39), IPRange (79) and MAC (59) are
+// documented and also checked by handlerx: a longer REST value is rejected with 400 Bad Request
+// before reaching the resolvers. Register a scalar of the same name without MaxLength to lift it.
+// The other scalars of a schema are strings unless registered, eg.
+//
+//	uuid := scalar.Scalar{Name: "UUID", Format: "uuid", Pattern: "^[0-9a-f]{8}(-[0-9a-f]{4}){3}-[0-9a-f]{12}$"}
+//	mapping := generated.RegisterHandlers(binder, srv, "", handlerx.WithScalar(uuid))
+//	plugin := restgen.NewDocPlugin("rest.yaml", "YAML", false, uuid)
+//
+// The same scalars should be registered in the program generating the code and in the server.
+// The gqlrest command documents the scalars of the Scalars section of its validator.yaml file, see FromConfig.
+package scalar
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/speedoops/go-gqlrest/config"
+)
+
+// OpenAPI types of the scalars
+const (
+	TypeString  = "string"
+	TypeInteger = "integer"
+	TypeNumber  = "number"
+	TypeBoolean = "boolean"
+)
+
+// ErrInvalid is returned by the coercions for a value which is not of the type of the scalar
+var ErrInvalid = errors.New("invalid value")
+
+// ErrOutOfRange is returned by the coercions for a number too large for the type of the scalar
+var ErrOutOfRange = errors.New("out of range")
+
+// Scalar is a GraphQL scalar of the REST routes
+type Scalar struct {
+	Name string
+	// Coerce converts a REST value into the value of a GraphQL variable, path and query parameters
+	// are strings, body parameters are decoded from JSON, with json.Number for numbers. If not set,
+	// the values are coerced by the OpenAPI type, and strings checked against the pattern and the lengths.
+	Coerce func(value interface{}) (interface{}, error)
+
+	// OpenAPI schema of the values, the type is "string" if not set
+	Type      string
+	Format    string
+	Pattern   string
+	MinLength *int64
+	MaxLength *int64
+
+	pattern *regexp.Regexp
+}
+
+// Registry is a set of scalars by name
+type Registry struct {
+	mu      sync.RWMutex
+	scalars map[string]*Scalar
+}
+
+// builtins is the registry of the built-in scalars, it is never modified
+var builtins = NewRegistry()
+
+// NewRegistry returns a registry of the built-in scalars,
+// the lengths of IP, IPRange and MAC are checked by Convert
+func NewRegistry() *Registry {
+	maxLength := func(n int64) *int64 { return &n }
+
+	r := &Registry{scalars: make(map[string]*Scalar)}
+	r.Register(Scalar{Name: "ID"})
+	r.Register(Scalar{Name: "String"})
+	r.Register(Scalar{Name: "Int", Type: TypeInteger, Format: "int64"})
+	r.Register(Scalar{Name: "Float", Type: TypeNumber, Format: "double"})
+	r.Register(Scalar{Name: "Boolean", Type: TypeBoolean})
+	r.Register(Scalar{Name: "Time", Format: "date-time", Coerce: Time})
+	// 2001:0db8:3c4d:0015:0000:0000:1a2f:1a2b
+	r.Register(Scalar{Name: "IP", Format: "ip", MaxLength: maxLength(39)})
+	r.Register(Scalar{Name: "IPRange", Format: "iprange", MaxLength: maxLength(79)})
+	r.Register(Scalar{Name: "MAC", Format: "mac", MaxLength: maxLength(59)})
+	return r
+}
+
+// FromConfig returns the scalars of the Scalars section of a validator.yaml file, see config.ScalarConf
+func FromConfig(confs []config.ScalarConf) []Scalar {
+	scalars := make([]Scalar, 0, len(confs))
+	for _, c := range confs {
+		scalars = append(scalars, Scalar{
+			Name:      c.Name,
+			Type:      c.Type,
+			Format:    c.Format,
+			Pattern:   c.Pattern,
+			MinLength: c.MinLength,
+			MaxLength: c.MaxLength,
+		})
+	}
+	return scalars
+}
+
+// Register adds a scalar to the registry, or replaces the scalar of the same name.
+// It panics if the pattern is not a valid regular expression.
+func (r *Registry) Register(s Scalar) {
+	if s.Type == "" {
+		s.Type = TypeString
+	}
+	if s.Pattern != "" {
+		s.pattern = regexp.MustCompile(s.Pattern)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.scalars[s.Name] = &s
+}
+
+// Lookup returns the registered scalar of a name, a nil registry has the built-in scalars
+func (r *Registry) Lookup(name string) (*Scalar, bool) {
+	if r == nil {
+		r = builtins
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	s, ok := r.scalars[name]
+	return s, ok
+}
+
+// Default is the scalar of the unregistered scalars of a schema, their values are strings
+func Default(name string) *Scalar {
+	return &Scalar{Name: name, Type: TypeString}
+}
+
+// Convert converts a REST value into the value of a GraphQL variable of the scalar
+func (s *Scalar) Convert(value interface{}) (interface{}, error) {
+	if s.Coerce != nil {
+		return s.Coerce(value)
+	}
+
+	switch s.Type {
+	case TypeInteger:
+		return Int(value)
+	case TypeNumber:
+		return Float(value)
+	case TypeBoolean:
+		return Boolean(value)
+	}
+
+	str, ok := value.(string)
+	if !ok {
+		// numbers and booleans of the body are accepted as their text
+		switch value.(type) {
+		case map[string]interface{}, []interface{}:
+			return nil, ErrInvalid
+		}
+		str = fmt.Sprintf("%v", value)
+	}
+	length := int64(utf8.RuneCountInString(str))
+	if s.MinLength != nil && length < *s.MinLength {
+		return nil, fmt.Errorf("must be at least %d characters long", *s.MinLength)
+	}
+	if s.MaxLength != nil && length > *s.MaxLength {
+		return nil, fmt.Errorf("must be at most %d characters long", *s.MaxLength)
+	}
+	if s.pattern != nil && !s.pattern.MatchString(str) {
+		return nil, fmt.Errorf("must match %s", s.Pattern)
+	}
+	return str, nil
+}
+
+// Int coerces a value into a 64 bits integer, as documented in the OpenAPI documents
+func Int(value interface{}) (interface{}, error) {
+	var s string
+	switch v := value.(type) {
+	case string:
+		s = strings.TrimSpace(v)
+	case json.Number:
+		s = v.String()
+	case int, int32, int64:
+		return v, nil
+	default:
+		return nil, ErrInvalid
+	}
+
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		if errors.Is(err, strconv.ErrRange) {
+			return nil, ErrOutOfRange
+		}
+		return nil, ErrInvalid
+	}
+	return json.Number(strconv.FormatInt(n, 10)), nil
+}
+
+// Float coerces a value into a finite floating point number
+func Float(value interface{}) (interface{}, error) {
+	var s string
+	switch v := value.(type) {
+	case string:
+		s = strings.TrimSpace(v)
+	case json.Number:
+		s = v.String()
+	case int, int32, int64, float32, float64:
+		return v, nil
+	default:
+		return nil, ErrInvalid
+	}
+
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsInf(f, 0) || math.IsNaN(f) {
+		if errors.Is(err, strconv.ErrRange) {
+			return nil, ErrOutOfRange
+		}
+		return nil, ErrInvalid
+	}
+	return json.Number(s), nil
+}
+
+// Boolean coerces a value into a boolean, "true", "false", "1" and "0" are accepted,
+// and an empty value, eg. the bare flag "?done", is true
+func Boolean(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case bool:
+		return v, nil
+	case string:
+		switch strings.ToLower(strings.TrimSpace(v)) {
+		case "true", "1", "":
+			return true, nil
+		case "false", "0":
+			return false, nil
+		}
+	}
+	return nil, ErrInvalid
+}
+
+// Time checks that a value is an RFC 3339 time, eg. "2006-01-02T15:04:05Z07:00"
+func Time(value interface{}) (interface{}, error) {
+	s, ok := value.(string)
+	if !ok {
+		return nil, ErrInvalid
+	}
+	if _, err := time.Parse(time.RFC3339Nano, s); err != nil {
+		return nil, errors.New("expected RFC 3339")
+	}
+	return s, nil
+}
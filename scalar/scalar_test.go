@@ -0,0 +1,129 @@
+package scalar
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/speedoops/go-gqlrest/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvert(t *testing.T) {
+	maxLength := int64(4)
+	registry := NewRegistry()
+	registry.Register(Scalar{Name: "Code", Pattern: "^[A-Z]+$", MaxLength: &maxLength})
+
+	tests := []struct {
+		Name     string
+		Scalar   string
+		Value    interface{}
+		Expected interface{}
+		Error    string
+	}{
+		{"int", "Int", " 42 ", json.Number("42"), ""},
+		{"int body", "Int", json.Number("-7"), json.Number("-7"), ""},
+		{"int invalid", "Int", "abc", nil, "invalid value"},
+		{"int fraction", "Int", json.Number("1.5"), nil, "invalid value"},
+		{"int range", "Int", "99999999999999999999", nil, "out of range"},
+		{"int bool", "Int", true, nil, "invalid value"},
+		{"float", "Float", "1.5e3", json.Number("1.5e3"), ""},
+		{"float NaN", "Float", "NaN", nil, "invalid value"},
+		{"boolean", "Boolean", "TRUE", true, ""},
+		{"boolean 0", "Boolean", "0", false, ""},
+		{"boolean flag", "Boolean", "", true, ""},
+		{"boolean body", "Boolean", false, false, ""},
+		{"boolean invalid", "Boolean", "yes", nil, "invalid value"},
+		{"time", "Time", "2022-07-01T08:00:00+08:00", "2022-07-01T08:00:00+08:00", ""},
+		{"time invalid", "Time", "2022-07-01", nil, "expected RFC 3339"},
+		{"id number", "ID", json.Number("12"), "12", ""},
+		{"string object", "String", map[string]interface{}{}, nil, "invalid value"},
+		{"ip", "IP", "2001:0db8:3c4d:0015:0000:0000:1a2f:1a2b:1", nil, "must be at most 39 characters long"},
+		{"pattern", "Code", "ABC", "ABC", ""},
+		{"pattern invalid", "Code", "abc", nil, "must match ^[A-Z]+$"},
+		{"pattern length", "Code", "ABCDE", nil, "must be at most 4 characters long"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			s, ok := registry.Lookup(tt.Scalar)
+			assert.True(t, ok)
+			v, err := s.Convert(tt.Value)
+			if tt.Error != "" {
+				assert.EqualError(t, err, tt.Error)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.Expected, v)
+		})
+	}
+
+	_, ok := registry.Lookup("UUID")
+	assert.False(t, ok)
+	// the registries are independent, a nil one has the built-in scalars only
+	_, ok = NewRegistry().Lookup("Code")
+	assert.False(t, ok)
+	_, ok = (*Registry)(nil).Lookup("Code")
+	assert.False(t, ok)
+	_, ok = (*Registry)(nil).Lookup("Time")
+	assert.True(t, ok)
+	v, err := Default("UUID").Convert("x")
+	assert.NoError(t, err)
+	assert.Equal(t, "x", v)
+}
+
+func TestBuiltinMaxLength(t *testing.T) {
+	long := "2001:0db8:3c4d:0015:0000:0000:1a2f:1a2b:1"
+
+	registry := NewRegistry()
+	ip, ok := registry.Lookup("IP")
+	require.True(t, ok)
+	_, err := ip.Convert(long)
+	assert.EqualError(t, err, "must be at most 39 characters long")
+
+	// the limit is lifted by registering the scalar again
+	registry.Register(Scalar{Name: "IP", Format: "ip"})
+	ip, ok = registry.Lookup("IP")
+	require.True(t, ok)
+	value, err := ip.Convert(long)
+	assert.NoError(t, err)
+	assert.Equal(t, long, value)
+}
+
+func TestFromConfig(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "validator.yaml")
+	require.NoError(t, ioutil.WriteFile(filename, []byte(`
+Validators:
+  - Name: name
+    MaxLength: 64
+Scalars:
+  - Name: UUID
+    Format: uuid
+    Pattern: "^[0-9a-f-]{36}$"
+  - Name: Port
+    Type: integer
+    Format: int32
+`), 0o644))
+
+	confs, err := config.ReadScalarConfig(filename)
+	require.NoError(t, err)
+
+	scalars := FromConfig(confs)
+	require.Len(t, scalars, 2)
+	assert.Equal(t, "UUID", scalars[0].Name)
+	assert.Equal(t, "uuid", scalars[0].Format)
+	assert.Equal(t, "^[0-9a-f-]{36}$", scalars[0].Pattern)
+
+	registry := NewRegistry()
+	for _, s := range scalars {
+		registry.Register(s)
+	}
+	uuid, ok := registry.Lookup("UUID")
+	require.True(t, ok)
+	assert.Equal(t, TypeString, uuid.Type)
+	port, ok := registry.Lookup("Port")
+	require.True(t, ok)
+	assert.Equal(t, TypeInteger, port.Type)
+}